@@ -28,12 +28,7 @@ func NewDetector(opts ...DetectorOption) *Detector {
 }
 
 func (d Detector) DetectEncoding(b []byte) (encoding.Encoding, string) {
-	results, err := detector.DetectAll(b)
-	if err != nil {
-		return nil, ""
-	}
-
-	results = d.resultFilter.filter(results)
+	results := d.results(b)
 	if len(results) == 0 {
 		return nil, ""
 	}
@@ -48,6 +43,44 @@ func (d Detector) DetectEncoding(b []byte) (encoding.Encoding, string) {
 	return enc, charset
 }
 
+// Candidate is a candidate encoding detected.
+type Candidate struct {
+	Encoding encoding.Encoding
+	// Charset is the name of the encoding, like "Shift_JIS".
+	Charset string
+	// Language is the language of the text, like "ja". It is empty for Unicode encodings.
+	Language string
+	// Confidence is the confidence of the candidate from 0 to 100.
+	Confidence int
+}
+
+// Candidates returns the candidate encodings of b ranked by confidence, or the preference by WithPrefer.
+// Candidates are filtered by the options, and those not supported by golang.org/x/text are omitted.
+func (d Detector) Candidates(b []byte) []Candidate {
+	var candidates []Candidate
+	for _, r := range d.results(b) {
+		enc, err := ianaindex.IANA.Encoding(r.Charset)
+		if err != nil || enc == nil {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			Encoding:   enc,
+			Charset:    r.Charset,
+			Language:   r.Language,
+			Confidence: r.Confidence,
+		})
+	}
+	return candidates
+}
+
+func (d Detector) results(b []byte) []chardet.Result {
+	results, err := detector.DetectAll(b)
+	if err != nil {
+		return nil
+	}
+	return d.resultFilter.filter(results)
+}
+
 func WithCharset(charsets ...string) DetectorOption {
 	return func(dc resultFilter) resultFilter {
 		dc.charsets = charsets
@@ -62,6 +95,15 @@ func WithLanguage(langs ...string) DetectorOption {
 	}
 }
 
+// WithMinConfidence omits candidates whose confidence is less than confidence, from 0 to 100.
+// When all the candidates are omitted, DetermineEncoding falls back to UTF-8 or windows-1252.
+func WithMinConfidence(confidence int) DetectorOption {
+	return func(dc resultFilter) resultFilter {
+		dc.minConfidence = confidence
+		return dc
+	}
+}
+
 func WithPrefer(f func(a, b chardet.Result) bool) DetectorOption {
 	return func(dc resultFilter) resultFilter {
 		dc.prefer = f
@@ -73,6 +115,8 @@ type resultFilter struct {
 	charsets  []string
 	languages []string
 	prefer    func(a, b chardet.Result) bool // return true if a is preferable than b
+
+	minConfidence int
 }
 
 func (dc resultFilter) filter(results []chardet.Result) []chardet.Result {
@@ -108,6 +152,17 @@ func (dc resultFilter) filter(results []chardet.Result) []chardet.Result {
 		results = filtered
 	}
 
+	if dc.minConfidence > 0 {
+		filtered := []chardet.Result{}
+		for _, r := range results {
+			if r.Confidence >= dc.minConfidence {
+				filtered = append(filtered, r)
+			}
+		}
+
+		results = filtered
+	}
+
 	if dc.prefer != nil {
 		sort.Slice(
 			results,
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/japanese"
 )
 
 func TestDetectEncoding(t *testing.T) {
@@ -29,3 +30,47 @@ func TestDetectEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestDetector_Candidates(t *testing.T) {
+	b, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte(testJapaneseText))
+
+	candidates := NewDetector().Candidates(b)
+	if assert.NotEmpty(t, candidates) {
+		assert.Equal(t, "Shift_JIS", candidates[0].Charset)
+		assert.Equal(t, "ja", candidates[0].Language)
+		assert.Equal(t, 100, candidates[0].Confidence)
+		assert.Equal(t, japanese.ShiftJIS, candidates[0].Encoding)
+	}
+	for i := 1; i < len(candidates); i++ {
+		assert.True(t, candidates[i-1].Confidence >= candidates[i].Confidence)
+		assert.NotNil(t, candidates[i].Encoding)
+	}
+
+	candidates = NewDetector(WithLanguage("zh")).Candidates(b)
+	for _, c := range candidates {
+		assert.Equal(t, "zh", c.Language)
+	}
+}
+
+func TestWithMinConfidence(t *testing.T) {
+	b := []byte("caf\xe9")
+
+	d := NewDetector()
+	enc, _ := d.DetectEncoding(b)
+	assert.NotNil(t, enc)
+	_, name, _ := DetermineEncoding(b, "", d.DetectEncoding)
+	assert.NotEqual(t, "windows-1252", name)
+
+	d = NewDetector(WithMinConfidence(90))
+	for _, c := range d.Candidates(b) {
+		assert.True(t, c.Confidence >= 90)
+	}
+	enc, _ = d.DetectEncoding(b)
+	assert.Nil(t, enc)
+	_, name, _ = DetermineEncoding(b, "", d.DetectEncoding)
+	assert.Equal(t, "windows-1252", name)
+
+	b, _ = japanese.EUCJP.NewEncoder().Bytes([]byte(testJapaneseText))
+	_, name, _ = DetermineEncoding(b, "", d.DetectEncoding)
+	assert.Equal(t, "EUC-JP", name)
+}
@@ -0,0 +1,310 @@
+package chardet
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/transform"
+)
+
+const (
+	// DefaultConfidenceThreshold is the confidence a StreamDetector stops at by default.
+	DefaultConfidenceThreshold = 90
+	// DefaultByteBudget is the number of bytes a StreamDetector reads at most by default.
+	DefaultByteBudget = 64 * 1024
+)
+
+// StreamDetector detects the encoding of a stream written to it in chunks.
+// It accumulates text outside HTML tags in chunks which are not ASCII-only as evidence,
+// and decides the encoding when the confidence reaches the threshold, the byte budget is used up,
+// or it is closed. The confidence is checked each time the evidence doubles, so that the cost does not
+// depend on the sizes of chunks. A BOM at the beginning of the stream decides the encoding immediately.
+type StreamDetector struct {
+	detector  *Detector
+	threshold int
+	budget    int
+
+	n        int
+	head     []byte
+	evidence []byte
+	text     []byte
+	// detectAt is the length of the evidence to run detection next, which doubles each time
+	// so that small writes do not make detection quadratic
+	detectAt int
+	// bomLen is the length of the BOM which decided the encoding
+	bomLen int
+
+	inTag bool
+	// escRest is the number of bytes remaining in an escape sequence of ISO-2022-JP
+	escRest int
+	// inKanji is true in double-byte mode of ISO-2022-JP, where '<' and '>' are parts of characters
+	inKanji bool
+
+	decided    bool
+	enc        encoding.Encoding
+	name       string
+	confidence int
+}
+
+type StreamOption func(*StreamDetector)
+
+// WithConfidenceThreshold sets the confidence, from 0 to 100, to decide the encoding without further evidence.
+func WithConfidenceThreshold(confidence int) StreamOption {
+	return func(s *StreamDetector) {
+		s.threshold = confidence
+	}
+}
+
+// WithByteBudget sets the number of bytes to decide the encoding at most.
+// n <= 0 means no budget, where the encoding is decided by the confidence or Close.
+func WithByteBudget(n int) StreamOption {
+	return func(s *StreamDetector) {
+		if n < 0 {
+			n = 0
+		}
+		s.budget = n
+	}
+}
+
+// NewStreamDetector returns a StreamDetector which detects encodings with d.
+// A nil d means NewDetector().
+func NewStreamDetector(d *Detector, opts ...StreamOption) *StreamDetector {
+	if d == nil {
+		d = NewDetector()
+	}
+	s := &StreamDetector{
+		detector:  d,
+		threshold: DefaultConfidenceThreshold,
+		budget:    DefaultByteBudget,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Write adds p as evidence. It does nothing after the encoding is decided.
+func (s *StreamDetector) Write(p []byte) (int, error) {
+	if s.decided {
+		return len(p), nil
+	}
+
+	n := len(p)
+	if s.budget > 0 && s.n+len(p) > s.budget {
+		p = p[:s.budget-s.n]
+	}
+	s.n += len(p)
+
+	if len(s.head) < 3 {
+		s.head = append(s.head, p[:min(len(p), 3-len(s.head))]...)
+		if s.detectBOM() {
+			return n, nil
+		}
+	}
+
+	if s.scan(p) && len(s.evidence) >= s.detectAt {
+		s.detect(false)
+		s.detectAt = 2 * len(s.evidence)
+	}
+	if !s.decided && s.budget > 0 && s.n >= s.budget {
+		s.detect(true)
+	}
+
+	return n, nil
+}
+
+// Close decides the encoding with the evidence so far.
+func (s *StreamDetector) Close() error {
+	if !s.decided {
+		s.detect(true)
+	}
+	return nil
+}
+
+// Decided reports whether the encoding is decided.
+func (s *StreamDetector) Decided() bool {
+	return s.decided
+}
+
+// Result returns the encoding decided, its name and the confidence. enc is nil if the stream is
+// ASCII-only or the encoding is not decided yet.
+//
+// If no candidate is found for non-ASCII evidence, UTF-8 or windows-1252 is returned with confidence 0,
+// as DetermineEncoding does.
+func (s *StreamDetector) Result() (enc encoding.Encoding, name string, confidence int) {
+	return s.enc, s.name, s.confidence
+}
+
+func (s *StreamDetector) detectBOM() bool {
+	for _, b := range boms {
+		if bytes.HasPrefix(s.head, b.bom) {
+			s.enc, s.name = charset.Lookup(b.enc)
+			s.confidence = 100
+			s.bomLen = len(b.bom)
+			s.decided = true
+			return true
+		}
+	}
+	return false
+}
+
+// scan appends the text outside tags in p to the evidence if it is not ASCII-only.
+// Escape sequences and double-byte characters of ISO-2022-JP are also evidence.
+func (s *StreamDetector) scan(p []byte) bool {
+	s.text = s.text[:0]
+	hasEvidence := false
+
+	for _, c := range p {
+		switch {
+		case s.escRest > 0:
+			if s.escRest == 2 {
+				// ESC $ B or ESC $ @ starts double-byte mode, ESC ( B or ESC ( J ends it
+				s.inKanji = c == '$'
+			}
+			s.escRest--
+			hasEvidence = true
+		case c == 0x1b:
+			s.escRest = 2
+			hasEvidence = true
+		case s.inKanji:
+			hasEvidence = true
+		case s.inTag:
+			if c == '>' {
+				s.inTag = false
+			}
+			continue
+		case c == '<':
+			s.inTag = true
+			continue
+		case c >= 0x80:
+			hasEvidence = true
+		}
+
+		s.text = append(s.text, c)
+	}
+
+	if !hasEvidence {
+		return false
+	}
+	s.evidence = append(s.evidence, s.text...)
+	return true
+}
+
+func (s *StreamDetector) detect(final bool) {
+	if len(s.evidence) == 0 {
+		s.decided = final
+		return
+	}
+
+	for _, r := range s.detector.results(s.evidence) {
+		if !final && r.Confidence < s.threshold {
+			return
+		}
+		enc, err := ianaindex.IANA.Encoding(r.Charset)
+		if err != nil || enc == nil {
+			continue
+		}
+		s.enc, s.name, s.confidence = enc, r.Charset, r.Confidence
+		s.decided = true
+		return
+	}
+
+	if final {
+		if utf8.Valid(trimPartialRune(s.evidence)) {
+			s.enc, s.name = encoding.Nop, "utf-8"
+		} else {
+			s.enc, s.name = charmap.Windows1252, "windows-1252"
+		}
+		s.decided = true
+	}
+}
+
+// trimPartialRune eliminates a partial UTF-8 rune at the end of b.
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i > len(b)-4; i-- {
+		c := b[i]
+		if c < 0x80 {
+			break
+		}
+		if utf8.RuneStart(c) {
+			return b[:i]
+		}
+	}
+	return b
+}
+
+// NewReader returns a reader which decodes r into UTF-8 by the encoding detected by s.
+// It reads r until s decides the encoding, and then decodes what is read and the rest of r.
+// A BOM which decides the encoding is removed.
+// Bytes are not decoded if s detects nothing. A nil s means NewStreamDetector(nil).
+func NewReader(r io.Reader, s *StreamDetector) io.Reader {
+	if s == nil {
+		s = NewStreamDetector(nil)
+	}
+	return &decodingReader{r: r, s: s}
+}
+
+type decodingReader struct {
+	r   io.Reader
+	s   *StreamDetector
+	buf bytes.Buffer
+	dec io.Reader
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if d.dec == nil {
+		d.prepare()
+	}
+	return d.dec.Read(p)
+}
+
+func (d *decodingReader) prepare() {
+	rest := d.r
+
+	chunk := make([]byte, 4096)
+	for !d.s.Decided() {
+		n, err := d.r.Read(chunk)
+		d.buf.Write(chunk[:n])
+		d.s.Write(chunk[:n])
+		if err == io.EOF {
+			rest = nil
+			break
+		}
+		if err != nil {
+			rest = &errReader{err}
+			break
+		}
+	}
+	d.s.Close()
+	d.buf.Next(d.s.bomLen)
+
+	var r io.Reader = &d.buf
+	if rest != nil {
+		r = io.MultiReader(r, rest)
+	}
+
+	if enc, _, _ := d.s.Result(); enc != nil && enc != encoding.Nop {
+		r = transform.NewReader(r, enc.NewDecoder())
+	}
+	d.dec = r
+}
+
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,208 @@
+package chardet
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const testJapaneseText = "吾輩は猫である。名前はまだ無い。どこで生れたかとんと見当がつかぬ。何でも薄暗いじめじめした所でニャーニャー泣いていた事だけは記憶している。"
+
+func testHTML() string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head>\n")
+	for i := 0; i < 30; i++ {
+		b.WriteString(`<link rel="stylesheet" href="/assets/style.css">` + "\n")
+	}
+	b.WriteString(`</head><body><img alt="猫" src="/cat.png">` + "\n")
+	b.WriteString("<p>" + testJapaneseText + "</p>\n")
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+func writeChunks(s *StreamDetector, b []byte, size int) {
+	for len(b) > 0 && !s.Decided() {
+		n := size
+		if n > len(b) {
+			n = len(b)
+		}
+		s.Write(b[:n])
+		b = b[n:]
+	}
+}
+
+func TestStreamDetector(t *testing.T) {
+	doc := testHTML()
+
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+	}{
+		{"Shift_JIS", japanese.ShiftJIS},
+		{"EUC-JP", japanese.EUCJP},
+		{"ISO-2022-JP", japanese.ISO2022JP},
+		{"UTF-8", encoding.Nop},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := test.enc.NewEncoder().Bytes([]byte(doc))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			s := NewStreamDetector(NewDetector(WithLanguage("ja", "")))
+			writeChunks(s, b, 100)
+			assert.True(t, s.Decided())
+
+			_, name, confidence := s.Result()
+			assert.Equal(t, test.name, name)
+			assert.True(t, confidence >= DefaultConfidenceThreshold, "confidence: %d", confidence)
+		})
+	}
+}
+
+func TestStreamDetector_ASCIIOnly(t *testing.T) {
+	s := NewStreamDetector(nil)
+	writeChunks(s, []byte(`<html><body><img alt="`+"\x82\xa0\x82\xa2"+`">Hello, world</body></html>`), 10)
+	assert.False(t, s.Decided())
+
+	s.Close()
+	assert.True(t, s.Decided())
+
+	enc, name, _ := s.Result()
+	assert.Nil(t, enc)
+	assert.Equal(t, "", name)
+}
+
+func TestStreamDetector_ByteBudget(t *testing.T) {
+	b, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte(testHTML()))
+
+	s := NewStreamDetector(nil, WithByteBudget(1500))
+	writeChunks(s, b, 100)
+	assert.True(t, s.Decided())
+
+	enc, _, _ := s.Result()
+	assert.Nil(t, enc)
+}
+
+func TestStreamDetector_NoByteBudget(t *testing.T) {
+	b, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte(testHTML()))
+
+	for _, n := range []int{0, -1} {
+		s := NewStreamDetector(nil, WithByteBudget(n))
+		s.Write([]byte(strings.Repeat("a", 100)))
+		assert.False(t, s.Decided())
+
+		writeChunks(s, b, 100)
+		assert.True(t, s.Decided())
+
+		_, name, _ := s.Result()
+		assert.Equal(t, "Shift_JIS", name)
+	}
+}
+
+func TestStreamDetector_SmallWrites(t *testing.T) {
+	b, _ := japanese.ShiftJIS.NewEncoder().Bytes([]byte(strings.Repeat(testJapaneseText, 50)))
+
+	// The threshold is never reached, so detection runs only when the evidence doubles
+	s := NewStreamDetector(nil, WithConfidenceThreshold(101), WithByteBudget(0))
+	detections := 0
+	for i := range b {
+		detectAt := s.detectAt
+		s.Write(b[i : i+1])
+		if s.detectAt != detectAt {
+			detections++
+		}
+	}
+	assert.False(t, s.Decided())
+	assert.LessOrEqual(t, detections, 16, "detection should run O(log n) times for %d bytes", len(b))
+
+	s.Close()
+	_, name, _ := s.Result()
+	assert.Equal(t, "Shift_JIS", name)
+}
+
+func TestStreamDetector_ConfidenceThreshold(t *testing.T) {
+	b, _ := japanese.EUCJP.NewEncoder().Bytes([]byte(testHTML()))
+
+	s := NewStreamDetector(NewDetector(WithLanguage("ja", "")), WithConfidenceThreshold(101))
+	writeChunks(s, b, 100)
+	assert.False(t, s.Decided())
+
+	s.Close()
+	_, name, _ := s.Result()
+	assert.Equal(t, "EUC-JP", name)
+}
+
+func TestStreamDetector_Fallback(t *testing.T) {
+	s := NewStreamDetector(NewDetector(WithCharset("no-such-charset")))
+	s.Write([]byte("caf\xe9 au lait"))
+	s.Close()
+
+	_, name, confidence := s.Result()
+	assert.Equal(t, "windows-1252", name)
+	assert.Equal(t, 0, confidence)
+}
+
+func TestStreamDetector_BOM(t *testing.T) {
+	b, _ := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(testHTML()))
+
+	s := NewStreamDetector(nil)
+	s.Write(b[:1])
+	assert.False(t, s.Decided())
+	s.Write(b[1:2])
+	assert.True(t, s.Decided())
+
+	_, name, confidence := s.Result()
+	assert.Equal(t, "utf-16le", name)
+	assert.Equal(t, 100, confidence)
+}
+
+func TestNewReader(t *testing.T) {
+	doc := testHTML()
+
+	for _, enc := range []encoding.Encoding{japanese.ShiftJIS, japanese.EUCJP, japanese.ISO2022JP, encoding.Nop} {
+		b, _ := enc.NewEncoder().Bytes([]byte(doc))
+
+		for _, r := range []io.Reader{bytes.NewReader(b), iotest.OneByteReader(bytes.NewReader(b))} {
+			got, err := ioutil.ReadAll(NewReader(r, NewStreamDetector(NewDetector(WithLanguage("ja", "")))))
+			if assert.NoError(t, err) {
+				assert.Equal(t, doc, string(got))
+			}
+		}
+	}
+
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"\xef\xbb\xbfhello", "hello"},
+		{"\xff\xfeh\x00i\x00", "hi"},
+		{"\xfe\xff\x00h\x00i", "hi"},
+		{"\xef\xbb\xbf", ""},
+	} {
+		for _, r := range []io.Reader{strings.NewReader(test.in), iotest.OneByteReader(strings.NewReader(test.in))} {
+			got, err := ioutil.ReadAll(NewReader(r, nil))
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.want, string(got), "%q", test.in)
+			}
+		}
+	}
+
+	got, err := ioutil.ReadAll(NewReader(strings.NewReader("Hello, world"), nil))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Hello, world", string(got))
+	}
+
+	_, err = ioutil.ReadAll(NewReader(iotest.TimeoutReader(strings.NewReader(strings.Repeat("a", 5000))), nil))
+	assert.Equal(t, iotest.ErrTimeout, err)
+}
@@ -0,0 +1,333 @@
+package giturl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxConfigIncludeDepth is the limit of nested include.path, same as git.
+const maxConfigIncludeDepth = 10
+
+// configVar is a variable in a git config file.
+type configVar struct {
+	// section and key are lowercased, while subsection is case-sensitive.
+	section, subsection, key string
+	value                    string
+	// noValue is true for variables without "=", which mean boolean true.
+	noValue bool
+}
+
+// readConfigFile reads the variables in the git config file at path, following include.path.
+// ref: config.c
+func readConfigFile(path string, fn func(v configVar)) error {
+	return readConfigFileDepth(path, 0, fn)
+}
+
+func readConfigFileDepth(path string, depth int, fn func(v configVar)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return readConfig(f, path, depth, fn)
+}
+
+func readConfig(r io.Reader, path string, depth int, fn func(v configVar)) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	p := &configParser{src: string(b), line: 1}
+	for {
+		v, ok, err := p.next()
+		if err != nil {
+			return fmt.Errorf("giturl: bad config line %d in %s: %w", p.line, path, err)
+		}
+		if !ok {
+			return nil
+		}
+
+		fn(v)
+
+		if v.section == "include" && v.subsection == "" && v.key == "path" && !v.noValue {
+			if err := includeConfig(path, v.value, depth, fn); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func includeConfig(from, path string, depth int, fn func(v configVar)) error {
+	if depth >= maxConfigIncludeDepth {
+		return fmt.Errorf("giturl: exceeded maximum include depth (%d) while including %s from %s", maxConfigIncludeDepth, path, from)
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(home, path[2:])
+	} else if !filepath.IsAbs(path) {
+		if from == "" {
+			// git ignores relative includes in configs not from files
+			return nil
+		}
+		path = filepath.Join(filepath.Dir(from), path)
+	}
+
+	err := readConfigFileDepth(path, depth+1, fn)
+	if os.IsNotExist(err) {
+		// git silently ignores missing included files
+		return nil
+	}
+	return err
+}
+
+type configParser struct {
+	src     string
+	pos     int
+	line    int
+	section string
+	subsec  string
+}
+
+const configEOF = -1
+
+func (p *configParser) peek() int {
+	if p.pos >= len(p.src) {
+		return configEOF
+	}
+	return int(p.src[p.pos])
+}
+
+func (p *configParser) get() int {
+	c := p.peek()
+	if c == configEOF {
+		return c
+	}
+	p.pos++
+	if c == '\r' && p.peek() == '\n' {
+		p.pos++
+		c = '\n'
+	}
+	if c == '\n' {
+		p.line++
+	}
+	return c
+}
+
+// next returns the next variable. ok is false at the end of the input.
+func (p *configParser) next() (v configVar, ok bool, err error) {
+	if p.pos == 0 && strings.HasPrefix(p.src, "\xef\xbb\xbf") {
+		p.pos = 3
+	}
+
+	for {
+		c := p.get()
+		switch {
+		case c == configEOF:
+			return v, false, nil
+
+		case c == '\n' || isConfigSpace(c):
+			continue
+
+		case c == '#' || c == ';':
+			p.skipLine()
+
+		case c == '[':
+			if err := p.parseSection(); err != nil {
+				return v, false, err
+			}
+
+		case isConfigAlpha(c):
+			if p.section == "" {
+				return v, false, fmt.Errorf("variable outside of sections")
+			}
+			p.pos--
+			return p.parseVariable()
+
+		default:
+			return v, false, fmt.Errorf("unexpected character %q", rune(c))
+		}
+	}
+}
+
+func (p *configParser) skipLine() {
+	for {
+		c := p.get()
+		if c == '\n' || c == configEOF {
+			return
+		}
+	}
+}
+
+// parseSection parses a section header like [section], [section "subsection"] or deprecated [section.subsection].
+func (p *configParser) parseSection() error {
+	var name strings.Builder
+	for {
+		c := p.get()
+		switch {
+		case c == ']':
+			section := strings.ToLower(name.String())
+			if section == "" {
+				return fmt.Errorf("empty section name")
+			}
+			p.section, p.subsec = section, ""
+			if i := strings.IndexByte(section, '.'); i >= 0 {
+				// [section.subsection] is case-insensitive as a whole
+				p.section, p.subsec = section[:i], section[i+1:]
+			}
+			return nil
+
+		case isConfigSpace(c):
+			return p.parseSubsection(name.String())
+
+		case isConfigAlnum(c) || c == '-' || c == '.':
+			name.WriteByte(byte(c))
+
+		default:
+			return fmt.Errorf("invalid section header")
+		}
+	}
+}
+
+func (p *configParser) parseSubsection(section string) error {
+	c := p.get()
+	for isConfigSpace(c) {
+		c = p.get()
+	}
+	if c != '"' {
+		return fmt.Errorf("invalid section header")
+	}
+
+	var sub strings.Builder
+	for {
+		c := p.get()
+		switch c {
+		case configEOF, '\n':
+			return fmt.Errorf("unterminated subsection")
+		case '"':
+			if p.get() != ']' {
+				return fmt.Errorf("invalid section header")
+			}
+			if section == "" {
+				return fmt.Errorf("empty section name")
+			}
+			p.section, p.subsec = strings.ToLower(section), sub.String()
+			return nil
+		case '\\':
+			c = p.get()
+			if c == configEOF || c == '\n' {
+				return fmt.Errorf("unterminated subsection")
+			}
+		}
+		sub.WriteByte(byte(c))
+	}
+}
+
+func (p *configParser) parseVariable() (v configVar, ok bool, err error) {
+	var key strings.Builder
+	c := p.get()
+	for isConfigAlnum(c) || c == '-' {
+		key.WriteByte(byte(c))
+		c = p.get()
+	}
+	for isConfigSpace(c) {
+		c = p.get()
+	}
+
+	v = configVar{section: p.section, subsection: p.subsec, key: strings.ToLower(key.String())}
+
+	switch c {
+	case '\n', configEOF:
+		v.noValue = true
+		return v, true, nil
+	case '#', ';':
+		p.skipLine()
+		v.noValue = true
+		return v, true, nil
+	case '=':
+		v.value, err = p.parseValue()
+		return v, err == nil, err
+	default:
+		return v, false, fmt.Errorf("invalid key %q", key.String()+string(rune(c)))
+	}
+}
+
+// parseValue parses a value after "=", unquoting and unescaping it.
+// Whitespaces outside quotes are trimmed at both ends, and comments are removed.
+func (p *configParser) parseValue() (string, error) {
+	var b strings.Builder
+	quoted := false
+	comment := false
+	spaces := 0
+
+	for {
+		c := p.get()
+		if c == configEOF || c == '\n' {
+			if quoted {
+				return "", fmt.Errorf("unterminated quote")
+			}
+			return b.String(), nil
+		}
+		if comment {
+			continue
+		}
+
+		if isConfigSpace(c) && !quoted {
+			if b.Len() > 0 {
+				spaces++
+			}
+			continue
+		}
+		if !quoted && (c == '#' || c == ';') {
+			comment = true
+			continue
+		}
+
+		for ; spaces > 0; spaces-- {
+			b.WriteByte(' ')
+		}
+
+		switch c {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			switch c := p.get(); c {
+			case '\n':
+				// line continuation
+			case 't':
+				b.WriteByte('\t')
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case '\\', '"':
+				b.WriteByte(byte(c))
+			default:
+				return "", fmt.Errorf("invalid escape sequence in value")
+			}
+		default:
+			b.WriteByte(byte(c))
+		}
+	}
+}
+
+func isConfigSpace(c int) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isConfigAlpha(c int) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+func isConfigAlnum(c int) bool {
+	return isConfigAlpha(c) || '0' <= c && c <= '9'
+}
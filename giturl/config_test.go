@@ -0,0 +1,101 @@
+package giturl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = "\xef\xbb\xbf" + `# comment
+[core]
+	bare = false ; trailing comment
+	editor = "vim -u  NONE"   # quoted
+	flag
+[URL "https://example.com/"]
+	insteadOf = ex:
+	InsteadOf = "example:" ; quoted
+[url "ssh://git@Example.COM/"]
+	pushInsteadOf = ex:
+[Old.Style-Sub]
+	k = v
+[alias]
+	lg = log \
+--oneline  "#not comment" \t\"
+	sp = a  b   c   
+[section "sub \"sec\" \\ x"]
+	k-1=v
+[include]
+	path = included.cfg
+	path = missing.cfg
+[crlf]` + "\r\n\tkey = value\r\n"
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "included.cfg"), []byte("[url \"git@host:\"]\n\tinsteadOf = h:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err := readConfigFile(path, func(v configVar) {
+		name := v.section
+		if v.subsection != "" {
+			name += "." + v.subsection
+		}
+		name += "." + v.key
+		if v.noValue {
+			got = append(got, name)
+		} else {
+			got = append(got, name+"="+v.value)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := exec.Command("git", "config", "--file", path, "--includes", "--list").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadConfig_Errors(t *testing.T) {
+	for _, s := range []string{
+		"key = value",
+		"[section",
+		"[section \"sub]",
+		"[section \"sub\"",
+		"[]",
+		"[s]\nkey = \"unterminated",
+		"[s]\nkey = \\x",
+		"[s]\n1key = v",
+		"[s]\nkey: v",
+	} {
+		err := readConfig(strings.NewReader(s), "test", 0, func(configVar) {})
+		if err == nil {
+			t.Errorf("%q should fail", s)
+		}
+	}
+}
+
+func TestReadConfigFile_IncludeLoop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte("[include]\n\tpath = config\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := readConfigFile(path, func(configVar) {}); err == nil {
+		t.Error("include loop should fail")
+	}
+}
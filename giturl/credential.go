@@ -0,0 +1,130 @@
+package giturl
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// redactedPassword replaces passwords in Redacted, same as url.URL.Redacted.
+const redactedPassword = "xxxxx"
+
+// Username returns the unescaped user name in the userinfo of g.
+func (g *GitURL) Username() string {
+	username := g.User
+	if i := strings.IndexByte(username, ':'); i >= 0 {
+		username = username[:i]
+	}
+	return unescapeUserinfo(username)
+}
+
+// Password returns the unescaped password in the userinfo of g, and whether it is set.
+func (g *GitURL) Password() (string, bool) {
+	i := strings.IndexByte(g.User, ':')
+	if i < 0 {
+		return "", false
+	}
+	return unescapeUserinfo(g.User[i+1:]), true
+}
+
+func unescapeUserinfo(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// WithCredentials returns a copy of g with the userinfo replaced by username and password.
+// An empty password results in the userinfo without password, and empty both in no userinfo.
+func (g *GitURL) WithCredentials(username, password string) *GitURL {
+	h := *g
+	switch {
+	case password != "":
+		h.User = url.UserPassword(username, password).String()
+	case username != "":
+		h.User = url.User(username).String()
+	default:
+		h.User = ""
+	}
+	return &h
+}
+
+// Redacted is like String but replaces the password with "xxxxx", which is safe for logs.
+func (g *GitURL) Redacted() string {
+	if _, ok := g.Password(); !ok {
+		return g.String()
+	}
+	return g.WithCredentials(g.Username(), redactedPassword).String()
+}
+
+var rxURLPassword = regexp.MustCompile(`(://[^/@:]*):[^/]*@`)
+
+// Redact returns giturl with the password replaced with "xxxxx". Unlike (*GitURL).Redacted,
+// giturl is not canonicalized, and passwords in malformed URLs are also redacted.
+func Redact(giturl string) string {
+	return rxURLPassword.ReplaceAllString(giturl, "$1:"+redactedPassword+"@")
+}
+
+// CredentialFunc returns credentials to access the repository g points to, like git credential helpers.
+// An empty username means no credentials.
+type CredentialFunc func(g *GitURL) (username, password string, err error)
+
+// StaticCredentials returns a CredentialFunc which always returns username and password.
+func StaticCredentials(username, password string) CredentialFunc {
+	return func(*GitURL) (string, string, error) {
+		return username, password, nil
+	}
+}
+
+// TokenCredentials returns a CredentialFunc which returns token as a password, with the user name
+// each hosting service expects for access tokens. It fails for hosts not registered by RegisterProviderHost.
+func TokenCredentials(token string) CredentialFunc {
+	return func(g *GitURL) (string, string, error) {
+		typ, ok := LookupProvider(g.Host)
+		if !ok {
+			return "", "", fmt.Errorf("%w: %q", ErrUnknownProvider, g.Host)
+		}
+
+		switch typ {
+		case ProviderGitLab:
+			return "oauth2", token, nil
+		case ProviderBitbucket:
+			return "x-token-auth", token, nil
+		default:
+			return "x-access-token", token, nil
+		}
+	}
+}
+
+// CloneURL returns the URL to clone g with credentials from cred embedded.
+// Credentials are embedded only into http:// and https:// URLs, as other protocols do not
+// accept passwords in URLs; others are returned as String does without calling cred.
+// The returned URL contains secrets, so use Redacted or Redact to log it.
+func (g *GitURL) CloneURL(cred CredentialFunc) (string, error) {
+	if cred == nil || (g.Scheme != "http" && g.Scheme != "https") {
+		return g.String(), nil
+	}
+
+	username, password, err := cred(g)
+	if err != nil {
+		return "", fmt.Errorf("giturl: getting credentials for %s: %w", g.Redacted(), err)
+	}
+	if username == "" {
+		return g.String(), nil
+	}
+	return g.WithCredentials(username, password).String(), nil
+}
+
+// HTTPSCloneURL returns g as an https:// URL like HTTPS with credentials from cred embedded.
+func (g *GitURL) HTTPSCloneURL(cred CredentialFunc) (string, error) {
+	s, err := g.HTTPS()
+	if err != nil {
+		return "", err
+	}
+	h, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return h.CloneURL(cred)
+}
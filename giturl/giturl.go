@@ -1,10 +1,13 @@
-// Package giturl provides ParseGitURL which parses remote URLs under the way
-// that git does.
+// Package giturl provides ParseGitURL and Parse which parse remote URLs under the way
+// that git does, and Resolver which rewrites them by insteadOf rules of git config.
 package giturl
 
 import (
+	"errors"
+	"fmt"
 	"net/url"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -15,14 +18,71 @@ var (
 	rxSCPLikeV6   = regexp.MustCompile(`^(.+?@)?\[(.+?)\]:(.*)`)
 )
 
+// Errors wrapped by *ParseError.
+var (
+	ErrEmptyURL    = errors.New("empty URL")
+	ErrNoHost      = errors.New("no host specified")
+	ErrNoPath      = errors.New("no path specified")
+	ErrInvalidPort = errors.New("invalid port")
+)
+
+// ParseError is returned by ParseGitURL for malformed URLs.
+// Err is one of ErrEmptyURL, ErrNoHost, ErrNoPath, ErrInvalidPort or an error from url.Parse.
+type ParseError struct {
+	// URL is the URL with the password redacted by Redact.
+	URL string
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("giturl: cannot parse %q: %v", e.URL, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// isWindows enables the interpretation of drive letters like "C:" as local paths, as git does on Windows.
+var isWindows = runtime.GOOS == "windows"
+
+// hasDosDrivePrefix reports whether path starts with a drive letter like "C:".
+func hasDosDrivePrefix(path string) bool {
+	return len(path) >= 2 && path[1] == ':' &&
+		('A' <= path[0] && path[0] <= 'Z' || 'a' <= path[0] && path[0] <= 'z')
+}
+
+// ParseGitURL parses a remote URL as git does, returning a *ParseError for malformed ones.
+// host includes the userinfo like "user@host", which may contain a password; use Parse to separate
+// them, and Redact to log URLs.
+//
+// URLs of remote helpers like "codecommit::us-east-1://repo" are exotic with proto set to the
+// transport name and path set to the address following "::", which is not parsed further.
 func ParseGitURL(giturl string) (proto string, host string, port uint, path string, exotic bool, err error) {
 	// ref: parse_connect_url() in connect.c
 
+	fail := func(e error) (string, string, uint, string, bool, error) {
+		return "", "", 0, "", false, &ParseError{URL: Redact(giturl), Err: e}
+	}
+
+	if giturl == "" {
+		return fail(ErrEmptyURL)
+	}
+
+	if transport, address, ok := splitRemoteHelper(giturl); ok {
+		if address == "" {
+			return fail(ErrNoPath)
+		}
+		return transport, "", 0, address, true, nil
+	}
+
 	if rxURLLike.MatchString(giturl) {
-		var u *url.URL
-		u, err = url.Parse(giturl)
+		u, err := url.Parse(giturl)
 		if err != nil {
-			return
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return fail(err)
 		}
 
 		proto = u.Scheme
@@ -35,35 +95,50 @@ func ParseGitURL(giturl string) (proto string, host string, port uint, path stri
 
 		if proto == "ssh" {
 			if m := rxHostAndPort.FindStringSubmatch(host); m != nil {
-				if port64, err := strconv.ParseUint(m[2], 10, 16); err == nil {
-					host = m[1]
-					port = uint(port64)
+				port64, err := strconv.ParseUint(m[2], 10, 16)
+				if err != nil {
+					return fail(fmt.Errorf("%w: %s", ErrInvalidPort, m[2]))
 				}
+				host = m[1]
+				port = uint(port64)
 			}
-			if host[0] == '[' && host[len(host)-1] == ']' {
+			if len(host) >= 2 && host[0] == '[' && host[len(host)-1] == ']' {
 				host = host[1 : len(host)-1]
 			}
 		}
 
-		if u.User != nil {
-			host = u.User.String() + "@" + host
-		}
-
-		if proto == "git" || proto == "ssh" {
-			if path[1] == '~' {
+		switch proto {
+		case "git", "ssh":
+			if host == "" {
+				return fail(ErrNoHost)
+			}
+			if path == "" {
+				return fail(ErrNoPath)
+			}
+			if len(path) > 1 && path[1] == '~' {
 				path = path[1:]
 			}
-		} else if proto == "file" {
+		case "file":
 			host = ""
 			path = u.Host + u.Path
-		} else {
+			if path == "" {
+				return fail(ErrNoPath)
+			}
+		default:
 			exotic = true
+			if host == "" && u.User == nil && isHierarchicalExoticScheme(proto) {
+				return fail(ErrNoHost)
+			}
+		}
+
+		if u.User != nil && proto != "file" {
+			host = u.User.String() + "@" + host
 		}
 	} else {
 		colon := strings.IndexByte(giturl, ':')
 		slash := strings.IndexByte(giturl, '/')
 
-		if colon > -1 && (slash == -1 || colon < slash) /*&& !hasDosDrivePrefix(giturl)*/ {
+		if colon > -1 && (slash == -1 || colon < slash) && !(isWindows && hasDosDrivePrefix(giturl)) {
 			// For SCP-like URLs, colon must appear and be before any slashes
 			// - user@host.xyz:path/to/repo.git/
 			// - host.xyz:path/to/repo.git/
@@ -78,7 +153,14 @@ func ParseGitURL(giturl string) (proto string, host string, port uint, path stri
 				host = giturl[:colon]
 				path = giturl[colon+1:]
 			}
-			if path[1] == '~' {
+			if host == "" || strings.HasSuffix(host, "@") {
+				return fail(ErrNoHost)
+			}
+			if path == "" {
+				return fail(ErrNoPath)
+			}
+			// git also does this for paths like "a~b", whose second character is "~"
+			if len(path) > 1 && path[1] == '~' {
 				path = path[1:]
 			}
 		} else {
@@ -89,3 +171,31 @@ func ParseGitURL(giturl string) (proto string, host string, port uint, path stri
 
 	return
 }
+
+// splitRemoteHelper splits a URL like "<transport>::<address>" which specifies the remote helper.
+// ref: transport_get() in transport.c
+func splitRemoteHelper(giturl string) (transport, address string, ok bool) {
+	for i := 0; i < len(giturl); i++ {
+		c := giturl[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' {
+			continue
+		}
+		if i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '-' || c == '.') {
+			continue
+		}
+		if i > 0 && strings.HasPrefix(giturl[i:], "::") {
+			return giturl[:i], giturl[i+2:], true
+		}
+		break
+	}
+	return "", "", false
+}
+
+// isHierarchicalExoticScheme reports whether URLs of scheme, handled by remote helpers, require hosts.
+func isHierarchicalExoticScheme(scheme string) bool {
+	switch scheme {
+	case "http", "https", "ftp", "ftps":
+		return true
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package giturl
 import "testing"
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -93,6 +94,14 @@ func TestParseGitURL(t *testing.T) {
 			checkParseGitURL(t, fmt.Sprintf("%s:///%s", proto, repo))
 			checkParseGitURL(t, fmt.Sprintf("%s:///~%s", proto, repo))
 		}
+
+		// The userinfo of file:// URLs is dropped, unlike git
+		url := "file://user@host/" + repo
+		if proto, host, _, path, _, err := ParseGitURL(url); err != nil {
+			t.Errorf("ParseGitURL(%q): %v", url, err)
+		} else if proto != "file" || host != "" || path != "host/"+repo {
+			t.Errorf("ParseGitURL(%q) = %q, %q, %q", url, proto, host, path)
+		}
 		for _, host := range []string{"nohost", "nohost:12", "[::1]", "[::1]:23", "[", "[:aa"} {
 			checkParseGitURL(t, fmt.Sprintf("./%s:%s", host, repo))
 			checkParseGitURL(t, fmt.Sprintf("./:%s/~%s", host, repo))
@@ -129,3 +138,109 @@ func TestParseGitURL_HTTP(t *testing.T) {
 		}
 	}
 }
+
+func TestParseGitURL_RemoteHelper(t *testing.T) {
+	tests := []struct {
+		url, proto, path string
+	}{
+		{"codecommit::us-east-1://repo", "codecommit", "us-east-1://repo"},
+		{"persistent-https::https://host/repo", "persistent-https", "https://host/repo"},
+		{"git+x.y-z::host:repo", "git+x.y-z", "host:repo"},
+		{"ext::ssh -p 2222 %S /repo", "ext", "ssh -p 2222 %S /repo"},
+	}
+
+	for _, test := range tests {
+		proto, host, port, path, exotic, err := ParseGitURL(test.url)
+		if err != nil {
+			t.Errorf("ParseGitURL(%q): %v", test.url, err)
+			continue
+		}
+		if proto != test.proto || host != "" || port != 0 || path != test.path || !exotic {
+			t.Errorf("ParseGitURL(%q) = (%q, %q, %d, %q, %v)", test.url, proto, host, port, path, exotic)
+		}
+	}
+
+	// Not remote helpers
+	for _, url := range []string{"1a::repo", "-a::repo", "[::1]:repo", "./a::b"} {
+		if proto, _, _, _, exotic, err := ParseGitURL(url); err != nil || exotic {
+			t.Errorf("ParseGitURL(%q) = (%q, %v, %v)", url, proto, exotic, err)
+		}
+	}
+}
+
+func TestParseGitURL_Errors(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"", ErrEmptyURL},
+		{"host:", ErrNoPath},
+		{":repo", ErrNoHost},
+		{"user@:repo", ErrNoHost},
+		{"ssh://[]/", nil},
+		{"ssh:///repo", ErrNoHost},
+		{"ssh://host", ErrNoPath},
+		{"ssh://host:99999/repo", ErrInvalidPort},
+		{"git://h", ErrNoPath},
+		{"git://", ErrNoHost},
+		{"file://", ErrNoPath},
+		{"https://", ErrNoHost},
+		{"codecommit::", ErrNoPath},
+		{"ssh://[::1/repo", nil},
+		{"git://host/%zz", nil},
+	}
+
+	for _, test := range tests {
+		_, _, _, _, _, err := ParseGitURL(test.url)
+		if err == nil {
+			t.Errorf("ParseGitURL(%q) should fail", test.url)
+			continue
+		}
+
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("ParseGitURL(%q): %v is not *ParseError", test.url, err)
+			continue
+		}
+		if perr.URL != test.url {
+			t.Errorf("ParseGitURL(%q): ParseError.URL = %q", test.url, perr.URL)
+		}
+		if test.want != nil && !errors.Is(err, test.want) {
+			t.Errorf("ParseGitURL(%q) = %v, want %v", test.url, err, test.want)
+		}
+	}
+}
+
+func TestParseGitURL_DosDrivePrefix(t *testing.T) {
+	defer func(v bool) { isWindows = v }(isWindows)
+
+	tests := []struct {
+		url       string
+		windows   bool
+		wantProto string
+		wantHost  string
+		wantPath  string
+	}{
+		{url: "C:/repo", windows: false, wantProto: "ssh", wantHost: "C", wantPath: "/repo"},
+		{url: "C:/repo", windows: true, wantProto: "file", wantPath: "C:/repo"},
+		{url: `c:\repo`, windows: true, wantProto: "file", wantPath: `c:\repo`},
+		{url: "C:repo", windows: true, wantProto: "file", wantPath: "C:repo"},
+		{url: "file://C:/repo", windows: true, wantProto: "file", wantPath: "C:/repo"},
+		{url: "host:repo", windows: true, wantProto: "ssh", wantHost: "host", wantPath: "repo"},
+		{url: "1:repo", windows: true, wantProto: "ssh", wantHost: "1", wantPath: "repo"},
+	}
+
+	for _, test := range tests {
+		isWindows = test.windows
+
+		proto, host, _, path, _, err := ParseGitURL(test.url)
+		if err != nil {
+			t.Errorf("ParseGitURL(%q): %v", test.url, err)
+			continue
+		}
+		if proto != test.wantProto || host != test.wantHost || path != test.wantPath {
+			t.Errorf("ParseGitURL(%q) (windows=%v) = (%q, %q, %q), want (%q, %q, %q)",
+				test.url, test.windows, proto, host, path, test.wantProto, test.wantHost, test.wantPath)
+		}
+	}
+}
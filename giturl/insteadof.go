@@ -0,0 +1,159 @@
+package giturl
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolver rewrites remote URLs by url.<base>.insteadOf and url.<base>.pushInsteadOf rules
+// of git config before parsing them.
+//
+// For each URL, the rule with the longest matching prefix is applied, and the earliest one among
+// those of the same length. Push URLs are rewritten by pushInsteadOf rules, falling back to
+// insteadOf rules if none of them match.
+type Resolver struct {
+	insteadOf     []rewriteRule
+	pushInsteadOf []rewriteRule
+}
+
+type rewriteRule struct {
+	base   string
+	prefix string
+}
+
+// NewResolver returns an empty Resolver, which does not rewrite any URLs.
+func NewResolver() *Resolver {
+	return &Resolver{}
+}
+
+// LoadResolver reads rules from git config files at paths in order, following include.path.
+// Conditional includes (includeIf) are not supported. Files which do not exist are ignored.
+func LoadResolver(paths ...string) (*Resolver, error) {
+	r := NewResolver()
+	for _, path := range paths {
+		err := readConfigFile(path, r.addConfigVar)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// LoadGlobalResolver reads rules from the system and global git config files.
+// See GlobalConfigPaths.
+func LoadGlobalResolver() (*Resolver, error) {
+	return LoadResolver(GlobalConfigPaths()...)
+}
+
+// GlobalConfigPaths returns the paths of the system and global git config files in the order git reads them,
+// respecting GIT_CONFIG_SYSTEM, GIT_CONFIG_GLOBAL, GIT_CONFIG_NOSYSTEM and XDG_CONFIG_HOME.
+func GlobalConfigPaths() []string {
+	var paths []string
+
+	if os.Getenv("GIT_CONFIG_NOSYSTEM") == "" {
+		if path := os.Getenv("GIT_CONFIG_SYSTEM"); path != "" {
+			paths = append(paths, path)
+		} else {
+			paths = append(paths, "/etc/gitconfig")
+		}
+	}
+
+	if path := os.Getenv("GIT_CONFIG_GLOBAL"); path != "" {
+		return append(paths, path)
+	}
+
+	home, _ := os.UserHomeDir()
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "git", "config"))
+	} else if home != "" {
+		paths = append(paths, filepath.Join(home, ".config", "git", "config"))
+	}
+	if home != "" {
+		paths = append(paths, filepath.Join(home, ".gitconfig"))
+	}
+
+	return paths
+}
+
+// ReadConfig reads rules from a git config read from r. Relative include.path in it is ignored.
+func (r *Resolver) ReadConfig(rd io.Reader) error {
+	return readConfig(rd, "", 0, r.addConfigVar)
+}
+
+func (r *Resolver) addConfigVar(v configVar) {
+	if v.section != "url" || v.subsection == "" || v.noValue {
+		return
+	}
+
+	switch v.key {
+	case "insteadof":
+		r.AddInsteadOf(v.subsection, v.value)
+	case "pushinsteadof":
+		r.AddPushInsteadOf(v.subsection, v.value)
+	}
+}
+
+// AddInsteadOf adds a rule which rewrites URLs starting with prefix to start with base,
+// like url.<base>.insteadOf = <prefix>.
+func (r *Resolver) AddInsteadOf(base, prefix string) {
+	r.insteadOf = append(r.insteadOf, rewriteRule{base: base, prefix: prefix})
+}
+
+// AddPushInsteadOf adds a rule which rewrites push URLs starting with prefix to start with base,
+// like url.<base>.pushInsteadOf = <prefix>.
+func (r *Resolver) AddPushInsteadOf(base, prefix string) {
+	r.pushInsteadOf = append(r.pushInsteadOf, rewriteRule{base: base, prefix: prefix})
+}
+
+// Rewrite returns giturl rewritten by insteadOf rules.
+func (r *Resolver) Rewrite(giturl string) string {
+	s, _ := rewriteURL(giturl, r.insteadOf)
+	return s
+}
+
+// RewritePush returns giturl to push to rewritten by pushInsteadOf rules, or by insteadOf rules if none match.
+func (r *Resolver) RewritePush(giturl string) string {
+	if s, ok := rewriteURL(giturl, r.pushInsteadOf); ok {
+		return s
+	}
+	return r.Rewrite(giturl)
+}
+
+// ParseGitURL rewrites giturl by insteadOf rules and parses it with ParseGitURL.
+func (r *Resolver) ParseGitURL(giturl string) (proto string, host string, port uint, path string, exotic bool, err error) {
+	return ParseGitURL(r.Rewrite(giturl))
+}
+
+// Parse rewrites giturl by insteadOf rules and parses it with Parse.
+func (r *Resolver) Parse(giturl string) (*GitURL, error) {
+	return Parse(r.Rewrite(giturl))
+}
+
+// ParsePush rewrites giturl to push to by pushInsteadOf or insteadOf rules and parses it with Parse.
+func (r *Resolver) ParsePush(giturl string) (*GitURL, error) {
+	return Parse(r.RewritePush(giturl))
+}
+
+// rewriteURL rewrites giturl by the rule with the longest matching prefix. Empty prefixes never match.
+// ref: alias_url() in remote.c
+func rewriteURL(giturl string, rules []rewriteRule) (string, bool) {
+	longest := -1
+	for i, rule := range rules {
+		if rule.prefix == "" || !strings.HasPrefix(giturl, rule.prefix) {
+			continue
+		}
+		if longest < 0 || len(rule.prefix) > len(rules[longest].prefix) {
+			longest = i
+		}
+	}
+
+	if longest < 0 {
+		return giturl, false
+	}
+	return rules[longest].base + giturl[len(rules[longest].prefix):], true
+}
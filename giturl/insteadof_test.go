@@ -0,0 +1,143 @@
+package giturl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testInsteadOfConfig = `[url "https://github.com/"]
+	insteadOf = gh:
+	insteadOf = github:
+[url "git@github.com:"]
+	pushInsteadOf = gh:
+[url "https://github.com/motemen/"]
+	insteadOf = gh:motemen/
+[url "git@github.com:motemen/"]
+	insteadOf = gh:
+[url "codecommit::us-east-1://"]
+	insteadOf = cc:
+`
+
+func TestResolver(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte(testInsteadOfConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := LoadResolver(path, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url, fetch, push string
+	}{
+		{"gh:foo/bar", "https://github.com/foo/bar", "git@github.com:foo/bar"},
+		{"github:foo/bar", "https://github.com/foo/bar", "https://github.com/foo/bar"},
+		{"gh:motemen/go-nuts", "https://github.com/motemen/go-nuts", "git@github.com:motemen/go-nuts"},
+		{"cc:repo", "codecommit::us-east-1://repo", "codecommit::us-east-1://repo"},
+		{"https://example.com/gh:", "https://example.com/gh:", "https://example.com/gh:"},
+	}
+
+	// Compare with git
+	gitDir := filepath.Join(dir, "repo")
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", gitDir}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if out, err := exec.Command("git", "init", "-q", gitDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	git("config", "include.path", path)
+
+	for i, test := range tests {
+		if got := r.Rewrite(test.url); got != test.fetch {
+			t.Errorf("Rewrite(%q) = %q, want %q", test.url, got, test.fetch)
+		}
+		if got := r.RewritePush(test.url); got != test.push {
+			t.Errorf("RewritePush(%q) = %q, want %q", test.url, got, test.push)
+		}
+
+		remote := fmt.Sprintf("r%d", i)
+		git("remote", "add", remote, test.url)
+		if got := git("remote", "get-url", remote); got != test.fetch {
+			t.Errorf("git rewrites %q to %q, want %q", test.url, got, test.fetch)
+		}
+		if got := git("remote", "get-url", "--push", remote); got != test.push {
+			t.Errorf("git rewrites %q to push to %q, want %q", test.url, got, test.push)
+		}
+	}
+}
+
+func TestResolver_Parse(t *testing.T) {
+	r := NewResolver()
+	if err := r.ReadConfig(strings.NewReader(testInsteadOfConfig)); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := r.Parse("gh:motemen/go-nuts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Scheme != "https" || g.Host != "github.com" || g.Path != "/motemen/go-nuts" {
+		t.Errorf("Parse = %+v", g)
+	}
+
+	g, err = r.ParsePush("gh:foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Scheme != "ssh" || g.User != "git" || g.Host != "github.com" || g.Path != "foo/bar" {
+		t.Errorf("ParsePush = %+v", g)
+	}
+
+	proto, host, _, path, exotic, err := r.ParseGitURL("cc:repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proto != "codecommit" || host != "" || path != "us-east-1://repo" || !exotic {
+		t.Errorf("ParseGitURL = %q, %q, %q, %v", proto, host, path, exotic)
+	}
+}
+
+func TestGlobalConfigPaths(t *testing.T) {
+	setenv := func(key, value string) {
+		if v, ok := os.LookupEnv(key); ok {
+			t.Cleanup(func() { os.Setenv(key, v) })
+		} else {
+			t.Cleanup(func() { os.Unsetenv(key) })
+		}
+		os.Setenv(key, value)
+	}
+
+	setenv("HOME", "/home/user")
+	setenv("XDG_CONFIG_HOME", "")
+	setenv("GIT_CONFIG_NOSYSTEM", "")
+	setenv("GIT_CONFIG_SYSTEM", "")
+	setenv("GIT_CONFIG_GLOBAL", "")
+
+	assertPaths := func(want ...string) {
+		t.Helper()
+		if got := GlobalConfigPaths(); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("GlobalConfigPaths() = %q, want %q", got, want)
+		}
+	}
+
+	assertPaths("/etc/gitconfig", "/home/user/.config/git/config", "/home/user/.gitconfig")
+
+	os.Setenv("XDG_CONFIG_HOME", "/xdg")
+	os.Setenv("GIT_CONFIG_SYSTEM", "/system")
+	assertPaths("/system", "/xdg/git/config", "/home/user/.gitconfig")
+
+	os.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	os.Setenv("GIT_CONFIG_GLOBAL", "/global")
+	assertPaths("/global")
+}
@@ -0,0 +1,174 @@
+package giturl
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// defaultGitPorts are ports omitted in keys.
+var defaultGitPorts = map[string]uint{
+	"ssh":   22,
+	"git":   9418,
+	"http":  80,
+	"https": 443,
+	"ftp":   21,
+	"ftps":  990,
+}
+
+// CanonicalKey returns the key of the repository giturl points to, which is the same for URLs of
+// the same repository in different forms. See (*GitURL).CanonicalKey.
+func CanonicalKey(giturl string) (string, error) {
+	g, err := Parse(giturl)
+	if err != nil {
+		return "", err
+	}
+	return g.CanonicalKey(), nil
+}
+
+// CanonicalKey returns the key of the repository g points to, like "github.com/foo/bar" for
+// "git@github.com:foo/bar.git", "ssh://git@github.com/foo/bar" and "https://github.com/foo/bar/".
+//
+// The key consists of the lowercased host, the port unless it is the default one of the scheme,
+// and the path without leading and trailing slashes and ".git". The scheme and the user are ignored.
+// Keys of local paths are cleaned paths without ".git", and those of remote helpers with opaque addresses
+// are the URLs as they are.
+func (g *GitURL) CanonicalKey() string {
+	if g.Host == "" {
+		if g.Helper != "" && g.Scheme == "file" {
+			// Opaque addresses of remote helpers
+			return g.Helper + "::" + g.Path
+		}
+
+		p := strings.TrimSuffix(strings.TrimRight(g.Path, "/"), ".git")
+		if p == "" {
+			p = "/"
+		}
+		p = path.Clean(p)
+
+		if g.Scheme == "file" {
+			return p
+		}
+		return g.Scheme + "://" + p
+	}
+
+	var b strings.Builder
+	if strings.Contains(g.Host, ":") {
+		b.WriteString("[" + strings.ToLower(g.Host) + "]")
+	} else {
+		b.WriteString(strings.ToLower(g.Host))
+	}
+	if g.Port != 0 && g.Port != defaultGitPorts[g.Scheme] {
+		b.WriteString(":" + strconv.FormatUint(uint64(g.Port), 10))
+	}
+
+	p := strings.TrimSuffix(strings.TrimRight(g.Path, "/"), ".git")
+	p = strings.Trim(p, "/")
+	if p != "" {
+		b.WriteString("/" + p)
+	}
+	return b.String()
+}
+
+// Equivalent reports whether git URLs a and b point to the same repository, that is,
+// they have the same CanonicalKey. It returns false if either of them cannot be parsed.
+func Equivalent(a, b string) bool {
+	ka, err := CanonicalKey(a)
+	if err != nil {
+		return false
+	}
+	kb, err := CanonicalKey(b)
+	if err != nil {
+		return false
+	}
+	return ka == kb
+}
+
+// Matcher matches git URLs against glob patterns of canonical keys, like "github.com/myorg/*".
+//
+// Patterns are split into segments by "/" and each segment is matched as path.Match does,
+// except that "**" matches zero or more segments. Hosts in patterns are matched case-insensitively,
+// and a ".git" suffix of a pattern is ignored.
+type Matcher struct {
+	patterns [][]string
+}
+
+// NewMatcher returns a Matcher which matches git URLs matching any of patterns.
+// It returns an error wrapping path.ErrBadPattern for malformed patterns.
+func NewMatcher(patterns ...string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, pattern := range patterns {
+		segs, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.patterns = append(m.patterns, segs)
+	}
+	return m, nil
+}
+
+// Match reports whether giturl matches any of the patterns. It returns false if giturl cannot be parsed.
+func (m *Matcher) Match(giturl string) bool {
+	key, err := CanonicalKey(giturl)
+	if err != nil {
+		return false
+	}
+
+	segs := strings.Split(key, "/")
+	for _, pattern := range m.patterns {
+		if matchSegments(pattern, segs) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether giturl matches pattern. See Matcher for the syntax of patterns.
+func Match(pattern, giturl string) (bool, error) {
+	m, err := NewMatcher(pattern)
+	if err != nil {
+		return false, err
+	}
+	return m.Match(giturl), nil
+}
+
+func compilePattern(pattern string) ([]string, error) {
+	p := strings.TrimSuffix(strings.TrimRight(pattern, "/"), ".git")
+	if p == "" {
+		return nil, fmt.Errorf("giturl: empty pattern")
+	}
+
+	segs := strings.Split(p, "/")
+	if !strings.HasPrefix(p, "/") {
+		segs[0] = strings.ToLower(segs[0])
+	}
+	for _, seg := range segs {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("giturl: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return segs, nil
+}
+
+func matchSegments(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -0,0 +1,129 @@
+package giturl
+
+import (
+	"errors"
+	"path"
+	"testing"
+)
+
+func TestCanonicalKey(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"git@github.com:foo/bar.git", "github.com/foo/bar"},
+		{"ssh://git@github.com/foo/bar", "github.com/foo/bar"},
+		{"ssh://git@GitHub.com:22/foo/bar.git/", "github.com/foo/bar"},
+		{"https://github.com/foo/bar/", "github.com/foo/bar"},
+		{"https://user@github.com:443/foo/bar.git", "github.com/foo/bar"},
+		{"git://github.com:9418/foo/bar", "github.com/foo/bar"},
+		{"persistent-https::https://github.com/foo/bar", "github.com/foo/bar"},
+		{"ssh://git@host:2222/foo/bar", "host:2222/foo/bar"},
+		{"https://host:22/foo/bar", "host:22/foo/bar"},
+		{"user@[::1]:foo/bar.git", "[::1]/foo/bar"},
+		{"host:~user/repo", "host/~user/repo"},
+		{"/srv/repos/../repo.git/", "/srv/repo"},
+		{"file:///srv/repo", "/srv/repo"},
+		{"./repo", "repo"},
+		{"codecommit::us-east-1://repo", "repo"},
+		{"ext::ssh host %S repo.git", "ext::ssh host %S repo.git"},
+	}
+
+	for _, test := range tests {
+		got, err := CanonicalKey(test.url)
+		if err != nil {
+			t.Errorf("CanonicalKey(%q): %v", test.url, err)
+		} else if got != test.want {
+			t.Errorf("CanonicalKey(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+
+	if _, err := CanonicalKey("host:"); err == nil {
+		t.Error("CanonicalKey(host:) should fail")
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"git@github.com:foo/bar.git", "ssh://git@github.com/foo/bar", true},
+		{"git@github.com:foo/bar.git", "https://github.com/foo/bar", true},
+		{"https://GITHUB.COM/foo/bar/", "git://github.com/foo/bar.git", true},
+		{"https://github.com/foo/bar", "https://github.com/foo/baz", false},
+		{"https://github.com/foo/bar", "https://github.com/Foo/bar", false},
+		{"https://github.com/foo/bar", "https://gitlab.com/foo/bar", false},
+		{"ssh://git@host:2222/foo/bar", "git@host:foo/bar", false},
+		{"/srv/repo.git", "file:///srv/repo", true},
+		{"host:", "host:", false},
+	}
+
+	for _, test := range tests {
+		if got := Equivalent(test.a, test.b); got != test.want {
+			t.Errorf("Equivalent(%q, %q) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		{"github.com/myorg/*", "git@github.com:myorg/repo.git", true},
+		{"github.com/myorg/*", "https://GitHub.com/myorg/repo/", true},
+		{"GitHub.com/myorg/*", "https://github.com/myorg/repo", true},
+		{"github.com/myorg/*", "https://github.com/MyOrg/repo", false},
+		{"github.com/myorg/*", "https://github.com/otherorg/repo", false},
+		{"github.com/myorg/*", "https://github.com/myorg", false},
+		{"github.com/myorg/repo.git", "https://github.com/myorg/repo", true},
+		{"github.com/myorg/go-*", "https://github.com/myorg/go-nuts", true},
+		{"github.com/myorg/go-*", "https://github.com/myorg/nuts", false},
+		{"*.example.com/*/*", "git@git.example.com:foo/bar", true},
+		{"gitlab.com/group/*", "https://gitlab.com/group/sub/repo", false},
+		{"gitlab.com/group/**", "https://gitlab.com/group/sub/repo", true},
+		{"gitlab.com/group/**", "https://gitlab.com/group/repo", true},
+		{"gitlab.com/**/repo", "https://gitlab.com/a/b/c/repo", true},
+		{"gitlab.com/**/repo", "https://gitlab.com/a/b/c/other", false},
+		{"host:2222/*/*", "ssh://git@host:2222/foo/bar", true},
+		{"host/*/*", "ssh://git@host:2222/foo/bar", false},
+		{"/srv/*", "/srv/repo.git", true},
+		{"github.com/myorg/*", "host:", false},
+	}
+
+	for _, test := range tests {
+		got, err := Match(test.pattern, test.url)
+		if err != nil {
+			t.Errorf("Match(%q, %q): %v", test.pattern, test.url, err)
+		} else if got != test.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", test.pattern, test.url, got, test.want)
+		}
+	}
+}
+
+func TestNewMatcher(t *testing.T) {
+	m, err := NewMatcher("github.com/myorg/*", "gitlab.com/myorg/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for url, want := range map[string]bool{
+		"git@github.com:myorg/repo.git":           true,
+		"https://gitlab.com/myorg/sub/repo.git":   true,
+		"https://bitbucket.org/myorg/repo.git":    false,
+		"https://github.com/myorg/repo/extra.git": false,
+	} {
+		if got := m.Match(url); got != want {
+			t.Errorf("Match(%q) = %v, want %v", url, got, want)
+		}
+	}
+
+	if _, err := NewMatcher("github.com/[myorg/*"); !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("NewMatcher with a bad pattern: %v", err)
+	}
+	if _, err := NewMatcher(""); err == nil {
+		t.Error("NewMatcher with an empty pattern should fail")
+	}
+}
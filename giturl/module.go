@@ -0,0 +1,218 @@
+package giturl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoGoImport is returned by ResolveImportPath when no go-import meta tag matches the import path.
+var ErrNoGoImport = errors.New("giturl: no go-import meta tag found")
+
+// ModulePath returns the likely Go module path of the repository g points to, like "github.com/owner/repo"
+// for "git@github.com:owner/repo.git". The host is lowercased, the port and ".git" are stripped,
+// and "/vN" is appended for major versions 2 and above.
+func (g *GitURL) ModulePath(major int) (string, error) {
+	if g.Host == "" {
+		return "", fmt.Errorf("giturl: %s has no host", g.Scheme)
+	}
+
+	path := strings.Trim(g.Path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	if path == "" || strings.HasPrefix(path, "~") {
+		return "", fmt.Errorf("giturl: invalid module path %q", g.Path)
+	}
+	for _, seg := range strings.Split(path, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", fmt.Errorf("giturl: invalid module path %q", g.Path)
+		}
+	}
+
+	modPath := strings.ToLower(g.Host) + "/" + path
+	if major >= 2 {
+		modPath += "/v" + strconv.Itoa(major)
+	}
+	return modPath, nil
+}
+
+// SplitPathVersion splits the major version suffix "/vN" (N >= 2) off from a module path.
+// major is 0 if path does not have one.
+func SplitPathVersion(path string) (prefix string, major int) {
+	i := strings.LastIndex(path, "/v")
+	if i < 0 {
+		return path, 0
+	}
+
+	v := path[i+2:]
+	if v == "" || v[0] == '0' || strings.Trim(v, "0123456789") != "" {
+		return path, 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 2 {
+		return path, 0
+	}
+	return path[:i], n
+}
+
+// RepoRoot is a repository root declared by a go-import meta tag.
+// https://go.dev/ref/mod#vcs-find
+type RepoRoot struct {
+	// Prefix is the import path corresponding to the root of the repository.
+	Prefix string
+	// VCS is the version control system, like "git".
+	VCS string
+	// RepoURL is the URL of the repository.
+	RepoURL string
+	// Subdir is the directory in the repository of the module, which is usually empty.
+	Subdir string
+}
+
+// GitURL parses RepoURL of r. It returns an error if r is not a git repository.
+func (r *RepoRoot) GitURL() (*GitURL, error) {
+	if r.VCS != "git" {
+		return nil, fmt.Errorf("giturl: %s is not a git repository but %s", r.Prefix, r.VCS)
+	}
+	return Parse(r.RepoURL)
+}
+
+// ResolveImportPath resolves importPath to the repository root by fetching
+// "https://<importPath>?go-get=1" with client and parsing go-import meta tags as the go command does.
+// If the prefix of the matching tag is shorter than importPath, the prefix is also fetched to verify the tag.
+// A "/vN" suffix needs no special treatment as it is matched as a part of importPath.
+// A nil client means http.DefaultClient.
+func ResolveImportPath(ctx context.Context, client *http.Client, importPath string) (*RepoRoot, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	importPath = strings.Trim(importPath, "/")
+	if importPath == "" {
+		return nil, fmt.Errorf("giturl: empty import path")
+	}
+
+	root, err := fetchRepoRoot(ctx, client, importPath)
+	if err != nil {
+		return nil, err
+	}
+	if root.Prefix == importPath {
+		return root, nil
+	}
+
+	// ref: repoRootForImportDynamic() in cmd/go/internal/vcs/vcs.go
+	verified, err := fetchRepoRoot(ctx, client, root.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("giturl: verifying go-import of %s: %w", importPath, err)
+	}
+	if *verified != *root {
+		return nil, fmt.Errorf("giturl: go-import of %s mismatches that of %s", importPath, root.Prefix)
+	}
+
+	return root, nil
+}
+
+func fetchRepoRoot(ctx context.Context, client *http.Client, importPath string) (*RepoRoot, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", "https://"+importPath+"?go-get=1", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// The go command does not care about the status code either, as some servers return
+	// meta tags with 404 for non-existent packages
+	roots, err := ParseGoImports(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("giturl: parsing %s: %w", resp.Request.URL, err)
+	}
+
+	return matchGoImport(roots, importPath)
+}
+
+// ParseGoImports parses go-import meta tags in the head of an HTML document.
+// Malformed tags are ignored.
+func ParseGoImports(r io.Reader) ([]*RepoRoot, error) {
+	var roots []*RepoRoot
+
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return roots, err
+			}
+			return roots, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.DataAtom == atom.Body {
+				return roots, nil
+			}
+			if t.DataAtom != atom.Meta {
+				continue
+			}
+
+			var name, content string
+			for _, a := range t.Attr {
+				switch strings.ToLower(a.Key) {
+				case "name":
+					name = a.Val
+				case "content":
+					content = a.Val
+				}
+			}
+			if name != "go-import" {
+				continue
+			}
+
+			f := strings.Fields(content)
+			if len(f) != 3 && len(f) != 4 {
+				continue
+			}
+			root := &RepoRoot{Prefix: f[0], VCS: f[1], RepoURL: f[2]}
+			if len(f) == 4 {
+				root.Subdir = f[3]
+			}
+			roots = append(roots, root)
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); atom.Lookup(name) == atom.Head {
+				return roots, nil
+			}
+		}
+	}
+}
+
+// matchGoImport returns the root whose prefix matches importPath.
+// Roots of "mod" are ignored, which are for module proxies.
+// ref: matchGoImport() in cmd/go/internal/vcs/vcs.go
+func matchGoImport(roots []*RepoRoot, importPath string) (*RepoRoot, error) {
+	var match *RepoRoot
+	for _, root := range roots {
+		if root.VCS == "mod" {
+			continue
+		}
+		if importPath != root.Prefix && !strings.HasPrefix(importPath, root.Prefix+"/") {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("giturl: multiple go-import meta tags for %s: %s and %s", importPath, match.Prefix, root.Prefix)
+		}
+		match = root
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoGoImport, importPath)
+	}
+	return match, nil
+}
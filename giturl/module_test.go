@@ -0,0 +1,180 @@
+package giturl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitURL_ModulePath(t *testing.T) {
+	tests := []struct {
+		url   string
+		major int
+		want  string
+	}{
+		{"git@github.com:motemen/go-nuts.git", 0, "github.com/motemen/go-nuts"},
+		{"https://GitHub.com/motemen/go-nuts", 1, "github.com/motemen/go-nuts"},
+		{"ssh://git@github.com:22/motemen/go-nuts.git/", 2, "github.com/motemen/go-nuts/v2"},
+		{"https://gitlab.com/group/sub/repo.git", 10, "gitlab.com/group/sub/repo/v10"},
+		{"persistent-https::https://go.googlesource.com/net", 0, "go.googlesource.com/net"},
+	}
+
+	for _, test := range tests {
+		g, err := Parse(test.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := g.ModulePath(test.major)
+		if err != nil {
+			t.Errorf("ModulePath(%q, %d): %v", test.url, test.major, err)
+		} else if got != test.want {
+			t.Errorf("ModulePath(%q, %d) = %q, want %q", test.url, test.major, got, test.want)
+		}
+	}
+
+	for _, url := range []string{"/srv/repo.git", "host:~user/repo", "https://host/", "https://host/a//b", "host:a/../b"} {
+		g, err := Parse(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := g.ModulePath(0); err == nil {
+			t.Errorf("ModulePath(%q) = %q, should fail", url, got)
+		}
+	}
+}
+
+func TestSplitPathVersion(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		major  int
+	}{
+		{"github.com/a/b", "github.com/a/b", 0},
+		{"github.com/a/b/v2", "github.com/a/b", 2},
+		{"github.com/a/b/v12", "github.com/a/b", 12},
+		{"github.com/a/b/v1", "github.com/a/b/v1", 0},
+		{"github.com/a/b/v0", "github.com/a/b/v0", 0},
+		{"github.com/a/b/v02", "github.com/a/b/v02", 0},
+		{"github.com/a/b/v2.0", "github.com/a/b/v2.0", 0},
+		{"github.com/a/b/vendor", "github.com/a/b/vendor", 0},
+		{"github.com/a/b/v", "github.com/a/b/v", 0},
+	}
+
+	for _, test := range tests {
+		prefix, major := SplitPathVersion(test.path)
+		if prefix != test.prefix || major != test.major {
+			t.Errorf("SplitPathVersion(%q) = (%q, %d), want (%q, %d)", test.path, prefix, major, test.prefix, test.major)
+		}
+	}
+}
+
+func TestParseGoImports(t *testing.T) {
+	doc := `<!DOCTYPE html>
+<html><head>
+<meta name="go-import" content="example.com/a git https://git.example.com/a">
+<meta name="go-import" content="example.com/b   mod   https://proxy.example.com">
+<META NAME="go-import" CONTENT="example.com/c git https://git.example.com/mono c/sub">
+<meta name="go-import" content="malformed">
+<meta name="go-source" content="example.com/a _ _ _">
+</head>
+<body>
+<meta name="go-import" content="example.com/d git https://git.example.com/d">
+</body></html>`
+
+	roots, err := ParseGoImports(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*RepoRoot{
+		{Prefix: "example.com/a", VCS: "git", RepoURL: "https://git.example.com/a"},
+		{Prefix: "example.com/b", VCS: "mod", RepoURL: "https://proxy.example.com"},
+		{Prefix: "example.com/c", VCS: "git", RepoURL: "https://git.example.com/mono", Subdir: "c/sub"},
+	}
+	if !reflect.DeepEqual(roots, want) {
+		t.Errorf("got %+v", roots)
+	}
+}
+
+func TestResolveImportPath(t *testing.T) {
+	var host string
+	metas := map[string]string{
+		"/mod":        "{{host}}/mod git https://git.example.com/mod",
+		"/mod/v2":     "{{host}}/mod git https://git.example.com/mod",
+		"/mod/sub":    "{{host}}/mod git https://git.example.com/mod",
+		"/proxied":    "{{host}}/proxied mod https://proxy.example.com\n{{host}}/proxied git ssh://git@git.example.com/proxied.git",
+		"/mismatch/a": "{{host}}/mismatch git https://git.example.com/a",
+		"/mismatch":   "{{host}}/mismatch git https://git.example.com/b",
+		"/multiple":   "{{host}}/multiple git https://git.example.com/a\n{{host}}/multiple git https://git.example.com/b",
+		"/hg":         "{{host}}/hg hg https://hg.example.com/hg",
+	}
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("go-get") != "1" {
+			http.Error(w, "go-get=1 missing", http.StatusBadRequest)
+			return
+		}
+
+		meta, ok := metas[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		fmt.Fprintln(w, "<html><head>")
+		for _, content := range strings.Split(meta, "\n") {
+			fmt.Fprintf(w, "<meta name=\"go-import\" content=\"%s\">\n", strings.Replace(content, "{{host}}", host, -1))
+		}
+		fmt.Fprintln(w, "</head></html>")
+	}))
+	defer ts.Close()
+
+	host = ts.Listener.Addr().String()
+	ctx := context.Background()
+
+	for _, path := range []string{"/mod", "/mod/v2", "/mod/sub"} {
+		root, err := ResolveImportPath(ctx, ts.Client(), host+path)
+		if err != nil {
+			t.Errorf("ResolveImportPath(%q): %v", path, err)
+			continue
+		}
+		want := RepoRoot{Prefix: host + "/mod", VCS: "git", RepoURL: "https://git.example.com/mod"}
+		if *root != want {
+			t.Errorf("ResolveImportPath(%q) = %+v", path, *root)
+		}
+	}
+
+	root, err := ResolveImportPath(ctx, ts.Client(), host+"/proxied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := root.GitURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modPath, _ := g.ModulePath(0); modPath != "git.example.com/proxied" {
+		t.Errorf("ModulePath() = %q", modPath)
+	}
+
+	if _, err := ResolveImportPath(ctx, ts.Client(), host+"/notfound"); !errors.Is(err, ErrNoGoImport) {
+		t.Errorf("ResolveImportPath(/notfound): %v", err)
+	}
+	for _, path := range []string{"/mismatch/a", "/multiple"} {
+		if root, err := ResolveImportPath(ctx, ts.Client(), host+path); err == nil {
+			t.Errorf("ResolveImportPath(%q) = %+v, should fail", path, root)
+		}
+	}
+
+	root, err = ResolveImportPath(ctx, ts.Client(), host+"/hg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := root.GitURL(); err == nil {
+		t.Error("GitURL() of hg should fail")
+	}
+}
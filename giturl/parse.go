@@ -0,0 +1,201 @@
+package giturl
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// GitURL is a parsed git remote URL.
+type GitURL struct {
+	// Scheme is the protocol, "ssh" for SCP-like URLs and "file" for local paths.
+	Scheme string
+	// User is the userinfo part, which is not unescaped.
+	User string
+	// Host is the hostname without brackets for IPv6 addresses.
+	Host string
+	// Port is 0 if not specified.
+	Port uint
+	// Path is the path of the repository. It is relative to the home directory
+	// if not starting with "/", like "~user/repo" or "repo" in "host:repo".
+	Path string
+	// Exotic is true for protocols other than ssh, git and file, which git handles with remote helpers.
+	Exotic bool
+	// Helper is the transport name of the remote helper specified explicitly like "codecommit::...".
+	// Other fields are parsed from the address following "::".
+	Helper string
+}
+
+// Parse parses a git remote URL like ParseGitURL, but returns the result as a *GitURL.
+// Unlike ParseGitURL, the user and the port are separated from the host for all protocols,
+// and the address of a remote helper URL is parsed as a URL. An address which is not a URL,
+// like "repo" in "codecommit::repo", results in a local path as it is.
+func Parse(giturl string) (*GitURL, error) {
+	transport, address, ok := splitRemoteHelper(giturl)
+	if !ok {
+		return parse(giturl)
+	}
+
+	if address == "" {
+		return nil, &ParseError{URL: Redact(giturl), Err: ErrNoPath}
+	}
+	g, err := parse(address)
+	if _, _, nested := splitRemoteHelper(address); nested || err != nil {
+		// The address is passed to the remote helper as it is
+		g = &GitURL{Scheme: "file", Path: address}
+	}
+	g.Helper = transport
+	g.Exotic = true
+	return g, nil
+}
+
+func parse(giturl string) (*GitURL, error) {
+	proto, host, port, path, exotic, err := ParseGitURL(giturl)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GitURL{
+		Scheme: proto,
+		Port:   port,
+		Path:   path,
+		Exotic: exotic,
+	}
+
+	if i := strings.LastIndexByte(host, '@'); i >= 0 {
+		g.User, host = host[:i], host[i+1:]
+	}
+
+	if proto != "ssh" && rxURLLike.MatchString(giturl) {
+		if m := rxHostAndPort.FindStringSubmatch(host); m != nil {
+			if port64, err := strconv.ParseUint(m[2], 10, 16); err == nil {
+				host = m[1]
+				g.Port = uint(port64)
+			}
+		}
+	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	g.Host = host
+
+	return g, nil
+}
+
+// String returns the canonical form of g.
+// SSH URLs are formatted in the SCP-like form only when the path is relative and no port is specified,
+// and local paths are formatted as file:// URLs when absolute unless following a remote helper.
+func (g *GitURL) String() string {
+	if g.Helper != "" {
+		if g.Scheme == "file" {
+			return g.Helper + "::" + g.Path
+		}
+		return g.Helper + "::" + g.withoutHelper().String()
+	}
+
+	switch {
+	case g.Scheme == "file":
+		if strings.HasPrefix(g.Path, "/") {
+			return "file://" + g.escapedPath(g.Path)
+		}
+		return g.Path
+
+	case g.Scheme == "ssh" && g.Port == 0 && !strings.HasPrefix(g.Path, "/") && !strings.HasPrefix(g.Path, "~"):
+		s, _ := g.SCPLike()
+		return s
+
+	default:
+		return g.urlString(g.Scheme, g.User, g.Port)
+	}
+}
+
+// SCPLike returns g in the SCP-like form, like "user@host:path/to/repo.git".
+// It returns an error if g has no host or has a port, which cannot be represented in the form.
+func (g *GitURL) SCPLike() (string, error) {
+	if g.Host == "" {
+		return "", fmt.Errorf("giturl: %s has no host", g.Scheme)
+	}
+	if g.Port != 0 {
+		return "", fmt.Errorf("giturl: port %d cannot be represented in SCP-like form", g.Port)
+	}
+
+	var b strings.Builder
+	if g.User != "" {
+		b.WriteString(g.User + "@")
+	}
+	if strings.Contains(g.Host, ":") {
+		b.WriteString("[" + g.Host + "]")
+	} else {
+		b.WriteString(g.Host)
+	}
+	b.WriteString(":" + g.Path)
+	return b.String(), nil
+}
+
+// SSH returns g as an ssh:// URL, keeping the user and the port.
+func (g *GitURL) SSH() (string, error) {
+	if g.Host == "" {
+		return "", fmt.Errorf("giturl: %s has no host", g.Scheme)
+	}
+	port := g.Port
+	if g.Scheme != "ssh" {
+		port = 0
+	}
+	return g.urlString("ssh", g.User, port), nil
+}
+
+// HTTPS returns g as an https:// URL, dropping the user and the port unless g is an HTTP(S) URL.
+func (g *GitURL) HTTPS() (string, error) {
+	if g.Host == "" {
+		return "", fmt.Errorf("giturl: %s has no host", g.Scheme)
+	}
+	if g.Scheme == "http" || g.Scheme == "https" {
+		return g.urlString("https", g.User, g.Port), nil
+	}
+	return g.urlString("https", "", 0), nil
+}
+
+// GitProtocol returns g as a git:// URL, dropping the user and the port unless g is a git:// URL.
+func (g *GitURL) GitProtocol() (string, error) {
+	if g.Host == "" {
+		return "", fmt.Errorf("giturl: %s has no host", g.Scheme)
+	}
+	if g.Scheme == "git" {
+		return g.urlString("git", "", g.Port), nil
+	}
+	return g.urlString("git", "", 0), nil
+}
+
+func (g *GitURL) withoutHelper() *GitURL {
+	h := *g
+	h.Helper = ""
+	return &h
+}
+
+func (g *GitURL) urlString(scheme, user string, port uint) string {
+	var b strings.Builder
+	b.WriteString(scheme + "://")
+	if user != "" {
+		b.WriteString(user + "@")
+	}
+	if strings.Contains(g.Host, ":") {
+		b.WriteString("[" + g.Host + "]")
+	} else {
+		b.WriteString(g.Host)
+	}
+	if port != 0 {
+		b.WriteString(":" + strconv.FormatUint(uint64(port), 10))
+	}
+
+	path := g.Path
+	if path != "" && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	b.WriteString(g.escapedPath(path))
+	return b.String()
+}
+
+func (g *GitURL) escapedPath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
@@ -0,0 +1,178 @@
+package giturl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want GitURL
+		str  string
+	}{
+		{
+			in:   "git@github.com:motemen/go-nuts.git",
+			want: GitURL{Scheme: "ssh", User: "git", Host: "github.com", Path: "motemen/go-nuts.git"},
+			str:  "git@github.com:motemen/go-nuts.git",
+		},
+		{
+			in:   "ssh://git@github.com/motemen/go-nuts.git",
+			want: GitURL{Scheme: "ssh", User: "git", Host: "github.com", Path: "/motemen/go-nuts.git"},
+			str:  "ssh://git@github.com/motemen/go-nuts.git",
+		},
+		{
+			in:   "git+ssh://User@[::1]:22/~repo",
+			want: GitURL{Scheme: "ssh", User: "User", Host: "::1", Port: 22, Path: "~repo"},
+			str:  "ssh://User@[::1]:22/~repo",
+		},
+		{
+			in:   "host:/~repo",
+			want: GitURL{Scheme: "ssh", Host: "host", Path: "~repo"},
+			str:  "ssh://host/~repo",
+		},
+		{
+			in:   "user@[::1]:re:po",
+			want: GitURL{Scheme: "ssh", User: "user", Host: "::1", Path: "re:po"},
+			str:  "user@[::1]:re:po",
+		},
+		{
+			in:   "git://host:9418/re/po",
+			want: GitURL{Scheme: "git", Host: "host", Port: 9418, Path: "/re/po"},
+			str:  "git://host:9418/re/po",
+		},
+		{
+			in:   "https://user@host:8443/re%20po.git",
+			want: GitURL{Scheme: "https", User: "user", Host: "host", Port: 8443, Path: "/re po.git", Exotic: true},
+			str:  "https://user@host:8443/re%20po.git",
+		},
+		{
+			in:   "file:///srv/repo.git",
+			want: GitURL{Scheme: "file", Path: "/srv/repo.git"},
+			str:  "file:///srv/repo.git",
+		},
+		{
+			in:   "/srv/repo.git",
+			want: GitURL{Scheme: "file", Path: "/srv/repo.git"},
+			str:  "file:///srv/repo.git",
+		},
+		{
+			in:   "./nohost:repo",
+			want: GitURL{Scheme: "file", Path: "./nohost:repo"},
+			str:  "./nohost:repo",
+		},
+		{
+			in:   "persistent-https::https://host/re/po",
+			want: GitURL{Scheme: "https", Host: "host", Path: "/re/po", Exotic: true, Helper: "persistent-https"},
+			str:  "persistent-https::https://host/re/po",
+		},
+		{
+			in:   "codecommit::us-east-1://profile@repo",
+			want: GitURL{Scheme: "us-east-1", User: "profile", Host: "repo", Exotic: true, Helper: "codecommit"},
+			str:  "codecommit::us-east-1://profile@repo",
+		},
+		{
+			in:   "codecommit::repo",
+			want: GitURL{Scheme: "file", Path: "repo", Exotic: true, Helper: "codecommit"},
+			str:  "codecommit::repo",
+		},
+		{
+			in:   "ext::ssh -p 2222 host %S /repo",
+			want: GitURL{Scheme: "file", Path: "ssh -p 2222 host %S /repo", Exotic: true, Helper: "ext"},
+			str:  "ext::ssh -p 2222 host %S /repo",
+		},
+		{
+			in:   "a::b::c",
+			want: GitURL{Scheme: "file", Path: "b::c", Exotic: true, Helper: "a"},
+			str:  "a::b::c",
+		},
+	}
+
+	for _, test := range tests {
+		g, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(*g, test.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.in, *g, test.want)
+		}
+		if s := g.String(); s != test.str {
+			t.Errorf("Parse(%q).String() = %q, want %q", test.in, s, test.str)
+		}
+
+		g2, err := Parse(g.String())
+		if err != nil {
+			t.Errorf("Parse(%q): %v", g.String(), err)
+			continue
+		}
+		if g2.String() != g.String() {
+			t.Errorf("String() does not round-trip: %q -> %q", g, g2)
+		}
+	}
+}
+
+func TestGitURL_Conversions(t *testing.T) {
+	tests := []struct {
+		in          string
+		scpLike     string
+		ssh         string
+		https       string
+		gitProtocol string
+	}{
+		{
+			in:          "git@github.com:motemen/go-nuts.git",
+			scpLike:     "git@github.com:motemen/go-nuts.git",
+			ssh:         "ssh://git@github.com/motemen/go-nuts.git",
+			https:       "https://github.com/motemen/go-nuts.git",
+			gitProtocol: "git://github.com/motemen/go-nuts.git",
+		},
+		{
+			in:          "https://github.com/motemen/go-nuts",
+			scpLike:     "github.com:/motemen/go-nuts",
+			ssh:         "ssh://github.com/motemen/go-nuts",
+			https:       "https://github.com/motemen/go-nuts",
+			gitProtocol: "git://github.com/motemen/go-nuts",
+		},
+		{
+			in:          "ssh://git@example.com:2222/~user/repo",
+			ssh:         "ssh://git@example.com:2222/~user/repo",
+			https:       "https://example.com/~user/repo",
+			gitProtocol: "git://example.com/~user/repo",
+		},
+		{
+			in: "/srv/repo.git",
+		},
+	}
+
+	for _, test := range tests {
+		g, err := Parse(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range []struct {
+			name string
+			fn   func() (string, error)
+			want string
+		}{
+			{"SCPLike", g.SCPLike, test.scpLike},
+			{"SSH", g.SSH, test.ssh},
+			{"HTTPS", g.HTTPS, test.https},
+			{"GitProtocol", g.GitProtocol, test.gitProtocol},
+		} {
+			got, err := c.fn()
+			if c.want == "" {
+				if err == nil {
+					t.Errorf("%q: %s() = %q, want error", test.in, c.name, got)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%q: %s(): %v", test.in, c.name, err)
+			} else if got != c.want {
+				t.Errorf("%q: %s() = %q, want %q", test.in, c.name, got, c.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package giturl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ProviderType is a type of git hosting services.
+type ProviderType string
+
+const (
+	ProviderGitHub    ProviderType = "github"
+	ProviderGitLab    ProviderType = "gitlab"
+	ProviderBitbucket ProviderType = "bitbucket"
+)
+
+// ErrUnknownProvider is returned by ParseRepository for hosts not registered.
+var ErrUnknownProvider = errors.New("giturl: unknown hosting provider")
+
+var (
+	providerHostsMu sync.RWMutex
+	providerHosts   = map[string]ProviderType{
+		"github.com":    ProviderGitHub,
+		"gitlab.com":    ProviderGitLab,
+		"bitbucket.org": ProviderBitbucket,
+	}
+)
+
+// RegisterProviderHost registers host as a hosting service of typ, like a GitHub Enterprise Server
+// or a self-hosted GitLab instance. An empty typ unregisters host.
+func RegisterProviderHost(host string, typ ProviderType) {
+	host = strings.ToLower(host)
+
+	providerHostsMu.Lock()
+	defer providerHostsMu.Unlock()
+
+	if typ == "" {
+		delete(providerHosts, host)
+	} else {
+		providerHosts[host] = typ
+	}
+}
+
+// LookupProvider returns the type of the hosting service at host.
+func LookupProvider(host string) (ProviderType, bool) {
+	providerHostsMu.RLock()
+	defer providerHostsMu.RUnlock()
+
+	typ, ok := providerHosts[strings.ToLower(host)]
+	return typ, ok
+}
+
+// Repository is a repository on a hosting service.
+type Repository struct {
+	Provider ProviderType
+	Host     string
+	// Owner is the user or the organization, which may contain "/" for GitLab subgroups.
+	Owner string
+	// Name is the name of the repository without ".git".
+	Name string
+
+	// HTTPSPort is the port of the web server, which is taken from https:// URLs.
+	// Zero means the default port.
+	HTTPSPort uint
+	// SSHPort is the port of the SSH server, which is taken from SSH URLs. Zero means the default port.
+	SSHPort uint
+	// SSHUser is the user to clone r via SSH, which is taken from SSH URLs. Empty means "git".
+	SSHUser string
+}
+
+// ParseRepository parses a git remote URL of a repository on a known hosting service.
+func ParseRepository(giturl string) (*Repository, error) {
+	g, err := Parse(giturl)
+	if err != nil {
+		return nil, err
+	}
+	return g.Repository()
+}
+
+// Repository returns the repository on the hosting service g points to.
+func (g *GitURL) Repository() (*Repository, error) {
+	typ, ok := LookupProvider(g.Host)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProvider, g.Host)
+	}
+
+	path := strings.Trim(g.Path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	segs := strings.Split(path, "/")
+
+	if len(segs) < 2 || (typ != ProviderGitLab && len(segs) != 2) {
+		return nil, fmt.Errorf("giturl: invalid %s repository path %q", typ, g.Path)
+	}
+	for _, seg := range segs {
+		if seg == "" {
+			return nil, fmt.Errorf("giturl: invalid %s repository path %q", typ, g.Path)
+		}
+	}
+
+	r := &Repository{
+		Provider: typ,
+		Host:     strings.ToLower(g.Host),
+		Owner:    strings.Join(segs[:len(segs)-1], "/"),
+		Name:     segs[len(segs)-1],
+	}
+
+	port := g.Port
+	if port == defaultGitPorts[g.Scheme] {
+		port = 0
+	}
+	switch g.Scheme {
+	case "https":
+		// Ports of http:// URLs are not carried over, as they do not serve https://
+		r.HTTPSPort = port
+	case "ssh":
+		r.SSHPort = port
+		if user := g.Username(); user != "git" {
+			r.SSHUser = user
+		}
+	}
+
+	return r, nil
+}
+
+// FullName returns the path of r like "owner/name".
+func (r *Repository) FullName() string {
+	return r.Owner + "/" + r.Name
+}
+
+// WebURL returns the URL of the top page of r.
+func (r *Repository) WebURL() string {
+	return "https://" + joinHostPort(r.Host, r.HTTPSPort) + "/" + r.FullName()
+}
+
+// HTTPSCloneURL returns the https:// URL to clone r.
+func (r *Repository) HTTPSCloneURL() string {
+	return r.WebURL() + ".git"
+}
+
+// SSHCloneURL returns the SCP-like URL to clone r with SSHUser, or "git" if it is empty.
+// The ssh:// form is returned instead if SSHPort is set, which SCP-like URLs cannot contain.
+func (r *Repository) SSHCloneURL() string {
+	user := r.SSHUser
+	if user == "" {
+		user = "git"
+	}
+	if r.SSHPort != 0 {
+		return "ssh://" + user + "@" + joinHostPort(r.Host, r.SSHPort) + "/" + r.FullName() + ".git"
+	}
+	return user + "@" + joinHostPort(r.Host, 0) + ":" + r.FullName() + ".git"
+}
+
+// joinHostPort is like net.JoinHostPort, but omits a zero port. IPv6 hosts are enclosed in brackets either way.
+func joinHostPort(host string, port uint) string {
+	if port != 0 {
+		return net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// BlobURL returns the URL of the file at path in ref, which is a branch, a tag or a commit.
+func (r *Repository) BlobURL(ref, path string) string {
+	switch r.Provider {
+	case ProviderGitLab:
+		return r.WebURL() + "/-/blob/" + escapePath(ref) + "/" + escapePath(path)
+	case ProviderBitbucket:
+		return r.WebURL() + "/src/" + escapePath(ref) + "/" + escapePath(path)
+	default:
+		return r.WebURL() + "/blob/" + escapePath(ref) + "/" + escapePath(path)
+	}
+}
+
+// TreeURL returns the URL of the directory at path in ref. An empty path means the root directory.
+func (r *Repository) TreeURL(ref, path string) string {
+	var s string
+	switch r.Provider {
+	case ProviderGitLab:
+		s = r.WebURL() + "/-/tree/" + escapePath(ref)
+	case ProviderBitbucket:
+		s = r.WebURL() + "/src/" + escapePath(ref)
+	default:
+		s = r.WebURL() + "/tree/" + escapePath(ref)
+	}
+	if path = strings.Trim(path, "/"); path != "" {
+		s += "/" + escapePath(path)
+	}
+	return s
+}
+
+// CommitURL returns the URL of the commit of sha.
+func (r *Repository) CommitURL(sha string) string {
+	switch r.Provider {
+	case ProviderGitLab:
+		return r.WebURL() + "/-/commit/" + url.PathEscape(sha)
+	case ProviderBitbucket:
+		return r.WebURL() + "/commits/" + url.PathEscape(sha)
+	default:
+		return r.WebURL() + "/commit/" + url.PathEscape(sha)
+	}
+}
+
+// escapePath escapes each segment of path.
+func escapePath(path string) string {
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,180 @@
+package giturl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseRepository(t *testing.T) {
+	RegisterProviderHost("GHE.Example.com", ProviderGitHub)
+	RegisterProviderHost("gitlab.example.com", ProviderGitLab)
+	defer RegisterProviderHost("ghe.example.com", "")
+	defer RegisterProviderHost("gitlab.example.com", "")
+
+	tests := []struct {
+		in   string
+		want Repository
+	}{
+		{"git@github.com:motemen/go-nuts.git", Repository{Provider: ProviderGitHub, Host: "github.com", Owner: "motemen", Name: "go-nuts"}},
+		{"https://GitHub.com/motemen/go-nuts", Repository{Provider: ProviderGitHub, Host: "github.com", Owner: "motemen", Name: "go-nuts"}},
+		{"ssh://git@github.com/motemen/go-nuts.git/", Repository{Provider: ProviderGitHub, Host: "github.com", Owner: "motemen", Name: "go-nuts"}},
+		{"git://github.com/motemen/go-nuts", Repository{Provider: ProviderGitHub, Host: "github.com", Owner: "motemen", Name: "go-nuts"}},
+		{"https://gitlab.com/group/sub/project.git", Repository{Provider: ProviderGitLab, Host: "gitlab.com", Owner: "group/sub", Name: "project"}},
+		{"git@bitbucket.org:team/repo.git", Repository{Provider: ProviderBitbucket, Host: "bitbucket.org", Owner: "team", Name: "repo"}},
+		{"git@ghe.example.com:org/repo.git", Repository{Provider: ProviderGitHub, Host: "ghe.example.com", Owner: "org", Name: "repo"}},
+		{"ssh://git@gitlab.example.com:2222/a/b/c/d.git", Repository{Provider: ProviderGitLab, Host: "gitlab.example.com", Owner: "a/b/c", Name: "d", SSHPort: 2222}},
+		{"https://ghe.example.com:8443/org/repo", Repository{Provider: ProviderGitHub, Host: "ghe.example.com", Owner: "org", Name: "repo", HTTPSPort: 8443}},
+		{"ssh://deploy@ghe.example.com:22/org/repo.git", Repository{Provider: ProviderGitHub, Host: "ghe.example.com", Owner: "org", Name: "repo", SSHUser: "deploy"}},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRepository(test.in)
+		if err != nil {
+			t.Errorf("ParseRepository(%q): %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(*r, test.want) {
+			t.Errorf("ParseRepository(%q) = %+v, want %+v", test.in, *r, test.want)
+		}
+	}
+
+	if _, err := ParseRepository("git@example.com:foo/bar.git"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("got %v, want ErrUnknownProvider", err)
+	}
+	for _, in := range []string{
+		"https://github.com/motemen",
+		"https://github.com/motemen/go-nuts/tree/master",
+		"https://gitlab.com//project",
+	} {
+		if _, err := ParseRepository(in); err == nil {
+			t.Errorf("ParseRepository(%q) should fail", in)
+		}
+	}
+}
+
+func TestRepository_URLs(t *testing.T) {
+	tests := []struct {
+		repo   Repository
+		web    string
+		https  string
+		ssh    string
+		blob   string
+		tree   string
+		commit string
+	}{
+		{
+			repo:   Repository{Provider: ProviderGitHub, Host: "github.com", Owner: "motemen", Name: "go-nuts"},
+			web:    "https://github.com/motemen/go-nuts",
+			https:  "https://github.com/motemen/go-nuts.git",
+			ssh:    "git@github.com:motemen/go-nuts.git",
+			blob:   "https://github.com/motemen/go-nuts/blob/feature/x/giturl/giturl%20test.go",
+			tree:   "https://github.com/motemen/go-nuts/tree/feature/x/giturl",
+			commit: "https://github.com/motemen/go-nuts/commit/4a6a251",
+		},
+		{
+			repo:   Repository{Provider: ProviderGitLab, Host: "gitlab.com", Owner: "group/sub", Name: "project"},
+			web:    "https://gitlab.com/group/sub/project",
+			https:  "https://gitlab.com/group/sub/project.git",
+			ssh:    "git@gitlab.com:group/sub/project.git",
+			blob:   "https://gitlab.com/group/sub/project/-/blob/feature/x/giturl/giturl%20test.go",
+			tree:   "https://gitlab.com/group/sub/project/-/tree/feature/x/giturl",
+			commit: "https://gitlab.com/group/sub/project/-/commit/4a6a251",
+		},
+		{
+			repo:   Repository{Provider: ProviderBitbucket, Host: "bitbucket.org", Owner: "team", Name: "repo"},
+			web:    "https://bitbucket.org/team/repo",
+			https:  "https://bitbucket.org/team/repo.git",
+			ssh:    "git@bitbucket.org:team/repo.git",
+			blob:   "https://bitbucket.org/team/repo/src/feature/x/giturl/giturl%20test.go",
+			tree:   "https://bitbucket.org/team/repo/src/feature/x/giturl",
+			commit: "https://bitbucket.org/team/repo/commits/4a6a251",
+		},
+	}
+
+	for _, test := range tests {
+		r := test.repo
+		for _, c := range []struct{ got, want string }{
+			{r.WebURL(), test.web},
+			{r.HTTPSCloneURL(), test.https},
+			{r.SSHCloneURL(), test.ssh},
+			{r.BlobURL("feature/x", "giturl/giturl test.go"), test.blob},
+			{r.TreeURL("feature/x", "/giturl/"), test.tree},
+			{r.CommitURL("4a6a251"), test.commit},
+		} {
+			if c.got != c.want {
+				t.Errorf("%s: got %q, want %q", r.Provider, c.got, c.want)
+			}
+		}
+	}
+}
+
+func TestRepository_URLs_Ports(t *testing.T) {
+	RegisterProviderHost("gitlab.corp", ProviderGitLab)
+	RegisterProviderHost("gitea.corp", ProviderGitHub)
+	defer RegisterProviderHost("gitlab.corp", "")
+	defer RegisterProviderHost("gitea.corp", "")
+	RegisterProviderHost("::1", ProviderGitHub)
+	defer RegisterProviderHost("::1", "")
+
+	tests := []struct {
+		in    string
+		web   string
+		https string
+		ssh   string
+	}{
+		{
+			in:    "ssh://git@gitlab.corp:2222/g/p.git",
+			web:   "https://gitlab.corp/g/p",
+			https: "https://gitlab.corp/g/p.git",
+			ssh:   "ssh://git@gitlab.corp:2222/g/p.git",
+		},
+		{
+			in:    "https://gitea.corp:3000/o/r",
+			web:   "https://gitea.corp:3000/o/r",
+			https: "https://gitea.corp:3000/o/r.git",
+			ssh:   "git@gitea.corp:o/r.git",
+		},
+		{
+			in:    "http://gitea.corp:8080/o/r.git",
+			web:   "https://gitea.corp/o/r",
+			https: "https://gitea.corp/o/r.git",
+			ssh:   "git@gitea.corp:o/r.git",
+		},
+		{
+			in:    "https://[::1]:8443/o/r",
+			web:   "https://[::1]:8443/o/r",
+			https: "https://[::1]:8443/o/r.git",
+			ssh:   "git@[::1]:o/r.git",
+		},
+		{
+			in:    "ssh://git@[::1]:2222/o/r.git",
+			web:   "https://[::1]/o/r",
+			https: "https://[::1]/o/r.git",
+			ssh:   "ssh://git@[::1]:2222/o/r.git",
+		},
+		{
+			in:    "deploy@gitlab.corp:g/p.git",
+			web:   "https://gitlab.corp/g/p",
+			https: "https://gitlab.corp/g/p.git",
+			ssh:   "deploy@gitlab.corp:g/p.git",
+		},
+	}
+
+	for _, test := range tests {
+		r, err := ParseRepository(test.in)
+		if err != nil {
+			t.Errorf("ParseRepository(%q): %v", test.in, err)
+			continue
+		}
+		for _, c := range []struct{ got, want string }{
+			{r.WebURL(), test.web},
+			{r.HTTPSCloneURL(), test.https},
+			{r.SSHCloneURL(), test.ssh},
+		} {
+			if c.got != c.want {
+				t.Errorf("%s: got %q, want %q", test.in, c.got, c.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package netutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrIdleTimeout is returned by reads and writes on connections closed by AccountingDialer.IdleTimeout.
+var ErrIdleTimeout = errors.New("connection closed after idle timeout")
+
+// AccountingDialer is a dialer whose connections count transferred bytes, limit bandwidth and
+// get closed when idle. Set Dialer.Control to NetworkBlocklist.Control to use with blocklists.
+//
+// Rates are in bytes per second, and zero means unlimited. Conn* rates are applied to each connection
+// and the others are applied to all the connections from the dialer in total.
+type AccountingDialer struct {
+	Dialer *net.Dialer
+
+	ReadRate      int64
+	WriteRate     int64
+	ConnReadRate  int64
+	ConnWriteRate int64
+
+	// IdleTimeout closes connections without any reads or writes for the duration.
+	IdleTimeout time.Duration
+
+	once        sync.Once
+	readBucket  *tokenBucket
+	writeBucket *tokenBucket
+
+	mu    sync.Mutex
+	conns map[*AccountingConn]struct{}
+}
+
+// ConnStats is a snapshot of statistics of an AccountingConn.
+type ConnStats struct {
+	LocalAddr    net.Addr
+	RemoteAddr   net.Addr
+	BytesRead    int64
+	BytesWritten int64
+	OpenedAt     time.Time
+	LastActiveAt time.Time
+}
+
+// Dial is like DialContext with context.Background().
+func (d *AccountingDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext dials address and returns an *AccountingConn.
+func (d *AccountingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.once.Do(func() {
+		d.readBucket = newTokenBucket(d.ReadRate)
+		d.writeBucket = newTokenBucket(d.WriteRate)
+	})
+
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c := &AccountingConn{
+		Conn:        conn,
+		dialer:      d,
+		readBucket:  newTokenBucket(d.ConnReadRate),
+		writeBucket: newTokenBucket(d.ConnWriteRate),
+		openedAt:    now,
+		lastActive:  now.UnixNano(),
+		closed:      make(chan struct{}),
+	}
+
+	if d.IdleTimeout > 0 {
+		c.idleMu.Lock()
+		c.idleTimer = time.AfterFunc(d.IdleTimeout, c.checkIdle)
+		c.idleMu.Unlock()
+	}
+
+	d.mu.Lock()
+	if d.conns == nil {
+		d.conns = map[*AccountingConn]struct{}{}
+	}
+	d.conns[c] = struct{}{}
+	d.mu.Unlock()
+
+	return c, nil
+}
+
+// Stats returns statistics of the open connections from d.
+func (d *AccountingDialer) Stats() []ConnStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := make([]ConnStats, 0, len(d.conns))
+	for c := range d.conns {
+		stats = append(stats, c.Stats())
+	}
+	return stats
+}
+
+// AccountingConn is a connection returned by AccountingDialer.
+type AccountingConn struct {
+	net.Conn
+	dialer *AccountingDialer
+
+	readBucket  *tokenBucket
+	writeBucket *tokenBucket
+
+	bytesRead    int64 // atomic
+	bytesWritten int64 // atomic
+	lastActive   int64 // atomic; unix nano
+	openedAt     time.Time
+
+	idleMu     sync.Mutex
+	idleTimer  *time.Timer
+	idleClosed int32 // atomic
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	closeErr  error
+}
+
+// Stats returns the current statistics of c.
+func (c *AccountingConn) Stats() ConnStats {
+	return ConnStats{
+		LocalAddr:    c.Conn.LocalAddr(),
+		RemoteAddr:   c.Conn.RemoteAddr(),
+		BytesRead:    atomic.LoadInt64(&c.bytesRead),
+		BytesWritten: atomic.LoadInt64(&c.bytesWritten),
+		OpenedAt:     c.openedAt,
+		LastActiveAt: time.Unix(0, atomic.LoadInt64(&c.lastActive)),
+	}
+}
+
+func (c *AccountingConn) Read(p []byte) (int, error) {
+	if max := c.maxChunk(c.readBucket, c.dialer.readBucket); max > 0 && len(p) > max {
+		p = p[:max]
+	}
+
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		atomic.AddInt64(&c.bytesRead, int64(n))
+		c.touch()
+		if werr := c.wait(n, c.readBucket, c.dialer.readBucket); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	return n, c.translateErr(err)
+}
+
+func (c *AccountingConn) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if max := c.maxChunk(c.writeBucket, c.dialer.writeBucket); max > 0 && len(chunk) > max {
+			chunk = chunk[:max]
+		}
+
+		if err := c.wait(len(chunk), c.writeBucket, c.dialer.writeBucket); err != nil {
+			return written, c.translateErr(err)
+		}
+
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if n > 0 {
+			atomic.AddInt64(&c.bytesWritten, int64(n))
+			c.touch()
+		}
+		if err != nil {
+			return written, c.translateErr(err)
+		}
+
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+func (c *AccountingConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+
+		c.idleMu.Lock()
+		if c.idleTimer != nil {
+			c.idleTimer.Stop()
+		}
+		c.idleMu.Unlock()
+
+		c.dialer.mu.Lock()
+		delete(c.dialer.conns, c)
+		c.dialer.mu.Unlock()
+
+		c.closeErr = c.Conn.Close()
+	})
+	return c.closeErr
+}
+
+func (c *AccountingConn) touch() {
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+}
+
+func (c *AccountingConn) checkIdle() {
+	idle := time.Since(time.Unix(0, atomic.LoadInt64(&c.lastActive)))
+	if idle < c.dialer.IdleTimeout {
+		c.idleMu.Lock()
+		c.idleTimer.Reset(c.dialer.IdleTimeout - idle)
+		c.idleMu.Unlock()
+		return
+	}
+
+	atomic.StoreInt32(&c.idleClosed, 1)
+	c.Close()
+}
+
+func (c *AccountingConn) translateErr(err error) error {
+	if err != nil && atomic.LoadInt32(&c.idleClosed) == 1 {
+		return ErrIdleTimeout
+	}
+	return err
+}
+
+// maxChunk returns the largest size of a read or write that buckets can handle at once.
+func (c *AccountingConn) maxChunk(buckets ...*tokenBucket) int {
+	max := 0
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+		if max == 0 || int(b.burst) < max {
+			max = int(b.burst)
+		}
+	}
+	return max
+}
+
+// wait waits until n bytes are allowed by all buckets, or c is closed.
+func (c *AccountingConn) wait(n int, buckets ...*tokenBucket) error {
+	var delay time.Duration
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+		if d := b.take(n); d > delay {
+			delay = d
+		}
+	}
+
+	if delay == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-c.closed:
+		return net.ErrClosed
+	}
+}
+
+// tokenBucket is a token bucket with the capacity of tokens for a second.
+// Tokens may be taken in advance, making the bucket negative.
+type tokenBucket struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:   float64(rate),
+		burst:  float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// take takes n tokens from b and returns the duration to wait until the tokens are available.
+func (b *tokenBucket) take(n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,137 @@
+package netutil
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func startEchoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestAccountingDialer_Stats(t *testing.T) {
+	l := startEchoServer(t)
+	defer l.Close()
+
+	d := &AccountingDialer{}
+
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	io.WriteString(conn, "hello")
+	b := make([]byte, 5)
+	_, err = io.ReadFull(conn, b)
+	assert.NoError(t, err)
+
+	stats := d.Stats()
+	if assert.Len(t, stats, 1) {
+		assert.Equal(t, int64(5), stats[0].BytesRead)
+		assert.Equal(t, int64(5), stats[0].BytesWritten)
+		assert.Equal(t, l.Addr().String(), stats[0].RemoteAddr.String())
+		assert.False(t, stats[0].LastActiveAt.Before(stats[0].OpenedAt))
+	}
+
+	conn.Close()
+	assert.Len(t, d.Stats(), 0)
+}
+
+func TestAccountingDialer_ConnWriteRate(t *testing.T) {
+	l := startEchoServer(t)
+	defer l.Close()
+
+	d := &AccountingDialer{ConnWriteRate: 20000}
+
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go io.Copy(io.Discard, conn)
+
+	start := time.Now()
+	n, err := conn.Write(make([]byte, 30000))
+	assert.NoError(t, err)
+	assert.Equal(t, 30000, n)
+
+	// 20000 bytes at once, then 10000 bytes in 0.5s
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(400*time.Millisecond))
+}
+
+func TestAccountingDialer_ReadRate(t *testing.T) {
+	l := startEchoServer(t)
+	defer l.Close()
+
+	d := &AccountingDialer{ReadRate: 10000}
+
+	conns := make([]net.Conn, 2)
+	for i := range conns {
+		conn, err := d.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write(make([]byte, 8000))
+		conns[i] = conn
+	}
+
+	start := time.Now()
+	for _, conn := range conns {
+		_, err := io.ReadFull(conn, make([]byte, 8000))
+		assert.NoError(t, err)
+	}
+
+	// 10000 bytes at once, then 6000 bytes in 0.6s shared by the connections
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(500*time.Millisecond))
+}
+
+func TestAccountingDialer_IdleTimeout(t *testing.T) {
+	l := startEchoServer(t)
+	defer l.Close()
+
+	d := &AccountingDialer{IdleTimeout: 100 * time.Millisecond}
+
+	conn, err := d.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// activities keep the connection open
+	for i := 0; i < 5; i++ {
+		time.Sleep(40 * time.Millisecond)
+		_, err := io.WriteString(conn, "x")
+		assert.NoError(t, err)
+	}
+
+	_, err = io.ReadFull(conn, make([]byte, 5))
+	assert.NoError(t, err)
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, ErrIdleTimeout)
+	assert.Len(t, d.Stats(), 0)
+}
@@ -2,6 +2,7 @@
 package netutil
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"syscall"
@@ -10,6 +11,16 @@ import (
 // PrivateNetworkBlocklist is a blocklist that blocks dialing to private networks.
 var PrivateNetworkBlocklist NetworkBlocklist
 
+// CloudMetadataBlocklist is a blocklist that blocks dialing to metadata endpoints of cloud providers.
+var CloudMetadataBlocklist NetworkBlocklist
+
+// MulticastBlocklist is a blocklist that blocks dialing to multicast addresses.
+var MulticastBlocklist NetworkBlocklist
+
+// StrictNetworkBlocklist is a blocklist that combines CloudMetadataBlocklist, MulticastBlocklist and PrivateNetworkBlocklist,
+// and also blocks the NAT64 well-known prefix, through which IPv4 addresses can be reached.
+var StrictNetworkBlocklist NetworkBlocklist
+
 // NetworkBlocklist is a blocklist that blocks dialing to specified networks.
 type NetworkBlocklist struct {
 	V4 []NamedNetwork
@@ -28,6 +39,15 @@ func (l NetworkBlocklist) Control(network, address string, c syscall.RawConn) er
 		return fmt.Errorf("cannot parse host %q", host)
 	}
 
+	return l.check(host, addr)
+}
+
+// CheckIP returns ErrBlocked if ip is contained in networks specified in l.
+func (l NetworkBlocklist) CheckIP(ip net.IP) error {
+	return l.check(ip.String(), ip)
+}
+
+func (l NetworkBlocklist) check(host string, addr net.IP) error {
 	if addr.To4() != nil {
 		for _, n := range l.V4 {
 			if n.IPNet.Contains(addr) {
@@ -68,9 +88,39 @@ func (e ErrBlocked) Error() string {
 	return message
 }
 
+// Is reports whether target is the category of the blocking network or its parent category,
+// so that errors.Is(err, CategoryCloudMetadata) can be used.
+func (e ErrBlocked) Is(target error) bool {
+	c, ok := target.(NetworkCategory)
+	return ok && c != "" && errors.Is(e.Network.Category, c)
+}
+
+// NetworkCategory is a category of NamedNetwork.
+// Categories are also errors to be matched against ErrBlocked with errors.Is.
+type NetworkCategory string
+
+const (
+	CategoryPrivate         NetworkCategory = "private"
+	CategoryCarrierGradeNAT NetworkCategory = "carrier-grade NAT"
+	CategoryCloudMetadata   NetworkCategory = "cloud metadata"
+	CategoryMulticast       NetworkCategory = "multicast"
+	CategoryNAT64           NetworkCategory = "NAT64"
+)
+
+func (c NetworkCategory) Error() string {
+	return fmt.Sprintf("host is blocked (%s)", string(c))
+}
+
+// Is reports whether target is the parent category of c.
+// CategoryCarrierGradeNAT is a subcategory of CategoryPrivate.
+func (c NetworkCategory) Is(target error) bool {
+	return c == CategoryCarrierGradeNAT && target == CategoryPrivate
+}
+
 type NamedNetwork struct {
-	IPNet *net.IPNet
-	Name  string
+	IPNet    *net.IPNet
+	Name     string
+	Category NetworkCategory
 }
 
 type unparsedNamedNetwork struct {
@@ -87,22 +137,35 @@ func MustParseCIDR(cidr string) *net.IPNet {
 	return ipNet
 }
 
-func init() {
-	PrivateNetworkBlocklist.V4 = make([]NamedNetwork, len(privateNetworksV4))
-	for i, p := range privateNetworksV4 {
-		PrivateNetworkBlocklist.V4[i] = NamedNetwork{
-			IPNet: MustParseCIDR(p.ip),
-			Name:  p.name,
+func parseNamedNetworks(unparsed []unparsedNamedNetwork, category NetworkCategory) []NamedNetwork {
+	networks := make([]NamedNetwork, len(unparsed))
+	for i, p := range unparsed {
+		networks[i] = NamedNetwork{
+			IPNet:    MustParseCIDR(p.ip),
+			Name:     p.name,
+			Category: category,
 		}
 	}
+	return networks
+}
 
-	PrivateNetworkBlocklist.V6 = make([]NamedNetwork, len(privateNetworksV6))
-	for i, p := range privateNetworksV6 {
-		PrivateNetworkBlocklist.V6[i] = NamedNetwork{
-			IPNet: MustParseCIDR(p.ip),
-			Name:  p.name,
-		}
+func init() {
+	PrivateNetworkBlocklist.V4 = parseNamedNetworks(privateNetworksV4, CategoryPrivate)
+	PrivateNetworkBlocklist.V4 = append(PrivateNetworkBlocklist.V4, parseNamedNetworks(carrierGradeNATNetworksV4, CategoryCarrierGradeNAT)...)
+	PrivateNetworkBlocklist.V6 = parseNamedNetworks(privateNetworksV6, CategoryPrivate)
+
+	CloudMetadataBlocklist.V4 = parseNamedNetworks(cloudMetadataNetworksV4, CategoryCloudMetadata)
+	CloudMetadataBlocklist.V6 = parseNamedNetworks(cloudMetadataNetworksV6, CategoryCloudMetadata)
+
+	MulticastBlocklist.V4 = parseNamedNetworks(multicastNetworksV4, CategoryMulticast)
+	MulticastBlocklist.V6 = parseNamedNetworks(multicastNetworksV6, CategoryMulticast)
+
+	// Networks are checked in order, so more specific categories come first
+	for _, l := range []NetworkBlocklist{CloudMetadataBlocklist, MulticastBlocklist, PrivateNetworkBlocklist} {
+		StrictNetworkBlocklist.V4 = append(StrictNetworkBlocklist.V4, l.V4...)
+		StrictNetworkBlocklist.V6 = append(StrictNetworkBlocklist.V6, l.V6...)
 	}
+	StrictNetworkBlocklist.V6 = append(StrictNetworkBlocklist.V6, parseNamedNetworks(nat64NetworksV6, CategoryNAT64)...)
 }
 
 // https://www.iana.org/assignments/iana-ipv4-special-registry/iana-ipv4-special-registry.xhtml
@@ -123,12 +186,16 @@ var privateNetworksV4 = []unparsedNamedNetwork{
 	{"198.51.100.0/24", "Documentation (TEST-NET-2)"},
 	{"203.0.113.0/24", "Documentation (TEST-NET-3)"},
 	{"192.0.0.0/29", "IPv4 Service Continuity Prefix"},
-	{"100.64.0.0/10", "Shared Address Space"},
 	{"192.0.0.170/32", "NAT64/DNS64 Discovery"},
 	{"192.0.0.171/32", "NAT64/DNS64 Discovery"},
 	{"192.0.0.8/32", "IPv4 dummy address"},
 }
 
+// https://www.rfc-editor.org/rfc/rfc6598
+var carrierGradeNATNetworksV4 = []unparsedNamedNetwork{
+	{"100.64.0.0/10", "Shared Address Space"},
+}
+
 // https://www.iana.org/assignments/iana-ipv6-special-registry/iana-ipv6-special-registry.xhtml
 var privateNetworksV6 = []unparsedNamedNetwork{
 	{"2001::/23", "IETF Protocol Assignments"},
@@ -145,3 +212,30 @@ var privateNetworksV6 = []unparsedNamedNetwork{
 	{"100::/64", "Discard-Only Address Block"},
 	{"64:ff9b:1::/48", "IPv4-IPv6 Translat."},
 }
+
+var cloudMetadataNetworksV4 = []unparsedNamedNetwork{
+	{"169.254.169.254/32", "Instance Metadata Service (AWS, GCP, Azure, OpenStack, etc.)"},
+	{"169.254.170.2/32", "Amazon ECS Task Metadata"},
+	{"100.100.100.200/32", "Alibaba Cloud Metadata Service"},
+	{"192.0.0.192/32", "Oracle Cloud Metadata Service"},
+}
+
+var cloudMetadataNetworksV6 = []unparsedNamedNetwork{
+	{"fd00:ec2::254/128", "Amazon EC2 Instance Metadata Service"},
+}
+
+// https://www.iana.org/assignments/multicast-addresses/multicast-addresses.xhtml
+var multicastNetworksV4 = []unparsedNamedNetwork{
+	{"224.0.0.0/4", "Multicast"},
+}
+
+// https://www.iana.org/assignments/ipv6-multicast-addresses/ipv6-multicast-addresses.xhtml
+var multicastNetworksV6 = []unparsedNamedNetwork{
+	{"ff00::/8", "Multicast"},
+}
+
+// NAT64 prefixes are not private, but addresses in them may be translated into private IPv4 addresses.
+// https://www.rfc-editor.org/rfc/rfc6052
+var nat64NetworksV6 = []unparsedNamedNetwork{
+	{"64:ff9b::/96", "IPv4-IPv6 Translat."},
+}
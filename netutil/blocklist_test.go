@@ -105,3 +105,53 @@ func ExampleNetworkBlocklist_Control() {
 	fmt.Println(err)
 	// Output: Get "http://[::1]/": dial tcp [::1]:80: host is blocked (Loopback Address)
 }
+
+func TestNetworkBlocklist_Categories(t *testing.T) {
+	tests := []struct {
+		blocklist  NetworkBlocklist
+		addr       string
+		categories []NetworkCategory
+	}{
+		{PrivateNetworkBlocklist, "10.0.0.1", []NetworkCategory{CategoryPrivate}},
+		{PrivateNetworkBlocklist, "100.64.0.1", []NetworkCategory{CategoryCarrierGradeNAT, CategoryPrivate}},
+		{PrivateNetworkBlocklist, "169.254.169.254", []NetworkCategory{CategoryPrivate}},
+		{PrivateNetworkBlocklist, "224.0.0.1", nil},
+		{PrivateNetworkBlocklist, "64:ff9b::a00:1", nil},
+		{CloudMetadataBlocklist, "169.254.169.254", []NetworkCategory{CategoryCloudMetadata}},
+		{CloudMetadataBlocklist, "fd00:ec2::254", []NetworkCategory{CategoryCloudMetadata}},
+		{CloudMetadataBlocklist, "10.0.0.1", nil},
+		{MulticastBlocklist, "239.255.255.250", []NetworkCategory{CategoryMulticast}},
+		{MulticastBlocklist, "ff02::1", []NetworkCategory{CategoryMulticast}},
+		{StrictNetworkBlocklist, "169.254.169.254", []NetworkCategory{CategoryCloudMetadata}},
+		{StrictNetworkBlocklist, "100.100.100.200", []NetworkCategory{CategoryCloudMetadata}},
+		{StrictNetworkBlocklist, "100.64.0.1", []NetworkCategory{CategoryCarrierGradeNAT, CategoryPrivate}},
+		{StrictNetworkBlocklist, "224.0.0.251", []NetworkCategory{CategoryMulticast}},
+		{StrictNetworkBlocklist, "::1", []NetworkCategory{CategoryPrivate}},
+		{StrictNetworkBlocklist, "64:ff9b::a00:1", []NetworkCategory{CategoryNAT64}},
+		{StrictNetworkBlocklist, "93.184.216.34", nil},
+	}
+
+	categories := []NetworkCategory{CategoryPrivate, CategoryCarrierGradeNAT, CategoryCloudMetadata, CategoryMulticast, CategoryNAT64}
+
+	for _, test := range tests {
+		err := test.blocklist.CheckIP(net.ParseIP(test.addr))
+		if test.categories == nil {
+			assert.NoError(t, err, test.addr)
+			continue
+		}
+
+		assert.ErrorAs(t, err, &ErrBlocked{}, test.addr)
+		for _, c := range categories {
+			assert.Equal(t, containsCategory(test.categories, c), errors.Is(err, c), "%s is %q", test.addr, c)
+		}
+	}
+}
+
+func containsCategory(categories []NetworkCategory, c NetworkCategory) bool {
+	for _, d := range categories {
+		if c == d {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,337 @@
+package netutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net"
+	"sort"
+)
+
+// NetworkSet is a set of IP addresses represented by networks.
+// NetworkBlocklist.V4 and NetworkBlocklist.V6 can be converted to NetworkSet.
+//
+// Set operations return aggregated sets, that is, minimal sorted lists of CIDRs (IPv4 first) covering the results.
+// Names and categories of the networks are kept where a resulting network is a part of networks all with
+// the same name and category, or is covered by a single network.
+type NetworkSet []NamedNetwork
+
+// Union returns the set of addresses in s or t.
+func (s NetworkSet) Union(t NetworkSet) NetworkSet {
+	return aggregateRanges(append(s.ranges(), t.ranges()...))
+}
+
+// Intersect returns the set of addresses in both s and t.
+// Names and categories come from the networks of s, even if those of t are narrower, as Difference does.
+func (s NetworkSet) Intersect(t NetworkSet) NetworkSet {
+	var result []ipRange
+	tRanges := t.ranges()
+	for _, a := range s.ranges() {
+		for _, b := range tRanges {
+			if a.bits != b.bits || a.last.less(b.first) || b.last.less(a.first) {
+				continue
+			}
+
+			r := a
+			if r.first.less(b.first) {
+				r.first = b.first
+			}
+			if b.last.less(r.last) {
+				r.last = b.last
+			}
+			result = append(result, r)
+		}
+	}
+
+	return aggregateRanges(result)
+}
+
+// Difference returns the set of addresses in s but not in t.
+func (s NetworkSet) Difference(t NetworkSet) NetworkSet {
+	remaining := s.ranges()
+	for _, b := range t.ranges() {
+		next := make([]ipRange, 0, len(remaining))
+		for _, a := range remaining {
+			if a.bits != b.bits || a.last.less(b.first) || b.last.less(a.first) {
+				next = append(next, a)
+				continue
+			}
+			if a.first.less(b.first) {
+				next = append(next, ipRange{bits: a.bits, first: a.first, last: b.first.prev(), label: a.label})
+			}
+			if b.last.less(a.last) {
+				next = append(next, ipRange{bits: a.bits, first: b.last.next(), last: a.last, label: a.label})
+			}
+		}
+		remaining = next
+	}
+
+	return aggregateRanges(remaining)
+}
+
+// Aggregate returns the minimal set of CIDRs which covers s.
+func (s NetworkSet) Aggregate() NetworkSet {
+	return aggregateRanges(s.ranges())
+}
+
+// Contains reports whether all addresses in t are in s.
+func (s NetworkSet) Contains(t NetworkSet) bool {
+	return len(t.Difference(s)) == 0
+}
+
+// ContainsIP reports whether ip is in s.
+func (s NetworkSet) ContainsIP(ip net.IP) bool {
+	for _, n := range s {
+		if n.IPNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RangeToCIDRs returns the minimal list of CIDRs which covers addresses from first to last, inclusive.
+func RangeToCIDRs(first, last net.IP) ([]*net.IPNet, error) {
+	r := ipRange{bits: 128}
+	if first.To4() != nil && last.To4() != nil {
+		r.bits = 32
+	}
+
+	var ok1, ok2 bool
+	r.first, ok1 = uint128FromIP(first, r.bits)
+	r.last, ok2 = uint128FromIP(last, r.bits)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("invalid IP range: %s - %s", first, last)
+	}
+	if r.last.less(r.first) {
+		return nil, fmt.Errorf("invalid IP range: %s is after %s", first, last)
+	}
+
+	return r.cidrs(), nil
+}
+
+// ipRange is an inclusive range of addresses of an address family, which has bits-bit addresses.
+// Addresses are stored right-aligned in uint128.
+type ipRange struct {
+	bits        int
+	first, last uint128
+	label
+}
+
+type label struct {
+	name     string
+	category NetworkCategory
+}
+
+func (s NetworkSet) ranges() []ipRange {
+	ranges := make([]ipRange, 0, len(s))
+	for _, n := range s {
+		ones, bits := n.IPNet.Mask.Size()
+		if bits != 32 && bits != 128 {
+			continue
+		}
+
+		first, ok := uint128FromIP(n.IPNet.IP, bits)
+		if !ok {
+			continue
+		}
+		first = first.and(hostMask(ones, bits).not())
+
+		ranges = append(ranges, ipRange{
+			bits:  bits,
+			first: first,
+			last:  first.or(hostMask(ones, bits)),
+			label: label{name: n.Name, category: n.Category},
+		})
+	}
+	return ranges
+}
+
+func (r ipRange) cidrs() []*net.IPNet {
+	var nets []*net.IPNet
+	first := r.first
+	for {
+		// largest block aligned at first
+		size := first.trailingZeros()
+		if size > r.bits {
+			size = r.bits
+		}
+		// shrink block to fit in the range
+		for size > 0 && r.last.less(first.or(hostMask(r.bits-size, r.bits))) {
+			size--
+		}
+
+		blockLast := first.or(hostMask(r.bits-size, r.bits))
+		nets = append(nets, &net.IPNet{
+			IP:   first.ip(r.bits),
+			Mask: net.CIDRMask(r.bits-size, r.bits),
+		})
+
+		if blockLast == r.last {
+			break
+		}
+		first = blockLast.next()
+	}
+	return nets
+}
+
+func aggregateRanges(ranges []ipRange) NetworkSet {
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].bits != ranges[j].bits {
+			return ranges[i].bits < ranges[j].bits
+		}
+		if ranges[i].first != ranges[j].first {
+			return ranges[i].first.less(ranges[j].first)
+		}
+		// wider ranges first
+		return ranges[j].last.less(ranges[i].last)
+	})
+
+	set := NetworkSet{}
+	for i := 0; i < len(ranges); {
+		merged := ranges[i]
+		sameLabel := true
+		j := i + 1
+		for ; j < len(ranges); j++ {
+			r := ranges[j]
+			if r.bits != merged.bits {
+				break
+			}
+			// not overlapping nor adjacent
+			if merged.last != maxUint128(merged.bits) && merged.last.next().less(r.first) {
+				break
+			}
+			if r.label != merged.label {
+				sameLabel = false
+			}
+			if merged.last.less(r.last) {
+				merged.last = r.last
+			}
+		}
+
+		for _, n := range merged.cidrs() {
+			l := merged.label
+			if !sameLabel {
+				l = coveringLabel(ranges[i:j], n)
+			}
+			set = append(set, NamedNetwork{IPNet: n, Name: l.name, Category: l.category})
+		}
+
+		i = j
+	}
+
+	return set
+}
+
+// coveringLabel returns the label of the narrowest range in ranges which covers n.
+func coveringLabel(ranges []ipRange, n *net.IPNet) label {
+	sub := NetworkSet{{IPNet: n}}.ranges()[0]
+
+	var found *ipRange
+	for i, r := range ranges {
+		if sub.first.less(r.first) || r.last.less(sub.last) {
+			continue
+		}
+		if found == nil || (!r.first.less(found.first) && !found.last.less(r.last)) {
+			found = &ranges[i]
+		}
+	}
+
+	if found == nil {
+		return label{}
+	}
+	return found.label
+}
+
+type uint128 struct {
+	hi, lo uint64
+}
+
+func uint128FromIP(ip net.IP, bits int) (uint128, bool) {
+	if bits == 32 {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return uint128{}, false
+		}
+		return uint128{lo: uint64(binary.BigEndian.Uint32(ip4))}, true
+	}
+
+	ip16 := ip.To16()
+	if ip16 == nil {
+		return uint128{}, false
+	}
+	return uint128{
+		hi: binary.BigEndian.Uint64(ip16[:8]),
+		lo: binary.BigEndian.Uint64(ip16[8:]),
+	}, true
+}
+
+func (u uint128) ip(bits int) net.IP {
+	if bits == 32 {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, uint32(u.lo))
+		return ip
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip[:8], u.hi)
+	binary.BigEndian.PutUint64(ip[8:], u.lo)
+	return ip
+}
+
+// hostMask returns the mask of host part of a network with prefix length ones.
+func hostMask(ones, bits int) uint128 {
+	return maxUint128(bits).shiftRight(ones)
+}
+
+func maxUint128(bits int) uint128 {
+	if bits == 32 {
+		return uint128{lo: 0xFFFFFFFF}
+	}
+	return uint128{hi: ^uint64(0), lo: ^uint64(0)}
+}
+
+func (u uint128) shiftRight(n int) uint128 {
+	switch {
+	case n >= 128:
+		return uint128{}
+	case n >= 64:
+		return uint128{lo: u.hi >> (n - 64)}
+	case n == 0:
+		return u
+	default:
+		return uint128{hi: u.hi >> n, lo: u.lo>>n | u.hi<<(64-n)}
+	}
+}
+
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || (u.hi == v.hi && u.lo < v.lo)
+}
+
+func (u uint128) and(v uint128) uint128 {
+	return uint128{hi: u.hi & v.hi, lo: u.lo & v.lo}
+}
+
+func (u uint128) or(v uint128) uint128 {
+	return uint128{hi: u.hi | v.hi, lo: u.lo | v.lo}
+}
+
+func (u uint128) not() uint128 {
+	return uint128{hi: ^u.hi, lo: ^u.lo}
+}
+
+func (u uint128) next() uint128 {
+	lo, carry := bits.Add64(u.lo, 1, 0)
+	return uint128{hi: u.hi + carry, lo: lo}
+}
+
+func (u uint128) prev() uint128 {
+	lo, borrow := bits.Sub64(u.lo, 1, 0)
+	return uint128{hi: u.hi - borrow, lo: lo}
+}
+
+func (u uint128) trailingZeros() int {
+	if u.lo != 0 {
+		return bits.TrailingZeros64(u.lo)
+	}
+	return 64 + bits.TrailingZeros64(u.hi)
+}
@@ -0,0 +1,214 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func networkSet(cidrs ...string) NetworkSet {
+	s := make(NetworkSet, len(cidrs))
+	for i, c := range cidrs {
+		s[i] = NamedNetwork{IPNet: MustParseCIDR(c), Name: c}
+	}
+	return s
+}
+
+func cidrStrings(s NetworkSet) []string {
+	ss := make([]string, len(s))
+	for i, n := range s {
+		ss[i] = n.IPNet.String()
+	}
+	return ss
+}
+
+func TestNetworkSet_Aggregate(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{
+			in:   []string{"10.0.0.0/25", "10.0.0.128/25"},
+			want: []string{"10.0.0.0/24"},
+		},
+		{
+			in:   []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.2.0/24"},
+			want: []string{"10.0.0.0/23", "10.0.2.0/24"},
+		},
+		{
+			in:   []string{"10.0.0.0/8", "10.1.0.0/16", "10.0.0.1/32"},
+			want: []string{"10.0.0.0/8"},
+		},
+		{
+			in:   []string{"2001:db8::/33", "2001:db8:8000::/33", "192.0.2.0/24"},
+			want: []string{"192.0.2.0/24", "2001:db8::/32"},
+		},
+		{
+			in:   []string{"0.0.0.0/1", "128.0.0.0/1"},
+			want: []string{"0.0.0.0/0"},
+		},
+		{
+			in:   []string{"::/1", "8000::/1"},
+			want: []string{"::/0"},
+		},
+		{
+			in:   []string{},
+			want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, cidrStrings(networkSet(test.in...).Aggregate()), "%v", test.in)
+	}
+}
+
+func TestNetworkSet_Operations(t *testing.T) {
+	a := networkSet("10.0.0.0/8", "192.168.0.0/16", "2001:db8::/32")
+	b := networkSet("10.1.0.0/16", "172.16.0.0/12", "2001:db8:1::/48")
+
+	assert.Equal(
+		t,
+		[]string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "2001:db8::/32"},
+		cidrStrings(a.Union(b)),
+	)
+	assert.Equal(
+		t,
+		[]string{"10.1.0.0/16", "2001:db8:1::/48"},
+		cidrStrings(a.Intersect(b)),
+	)
+	assert.Equal(
+		t,
+		[]string{
+			"10.0.0.0/16", "10.2.0.0/15", "10.4.0.0/14", "10.8.0.0/13", "10.16.0.0/12", "10.32.0.0/11", "10.64.0.0/10", "10.128.0.0/9",
+			"192.168.0.0/16",
+			"2001:db8::/48", "2001:db8:2::/47", "2001:db8:4::/46", "2001:db8:8::/45", "2001:db8:10::/44", "2001:db8:20::/43", "2001:db8:40::/42", "2001:db8:80::/41", "2001:db8:100::/40", "2001:db8:200::/39", "2001:db8:400::/38", "2001:db8:800::/37", "2001:db8:1000::/36", "2001:db8:2000::/35", "2001:db8:4000::/34", "2001:db8:8000::/33",
+		},
+		cidrStrings(a.Difference(b)),
+	)
+
+	assert.True(t, a.Contains(a.Intersect(b)))
+	assert.True(t, a.Union(b).Contains(b))
+	assert.False(t, a.Contains(b))
+	assert.True(t, a.Contains(NetworkSet{}))
+	assert.True(t, a.Difference(b).Union(a.Intersect(b)).Contains(a))
+
+	assert.True(t, a.ContainsIP(net.ParseIP("10.1.2.3")))
+	assert.False(t, a.ContainsIP(net.ParseIP("172.16.0.1")))
+}
+
+func TestNetworkSet_Names(t *testing.T) {
+	s := NetworkSet{
+		{IPNet: MustParseCIDR("192.0.0.0/24"), Name: "IETF Protocol Assignments"},
+		{IPNet: MustParseCIDR("192.0.0.0/29"), Name: "IPv4 Service Continuity Prefix"},
+		{IPNet: MustParseCIDR("10.0.0.0/9"), Name: "Private-Use"},
+		{IPNet: MustParseCIDR("10.128.0.0/9"), Name: "Private-Use"},
+		{IPNet: MustParseCIDR("198.18.0.0/16"), Name: "Benchmarking A"},
+		{IPNet: MustParseCIDR("198.19.0.0/16"), Name: "Benchmarking B"},
+	}
+
+	got := s.Aggregate()
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, "Private-Use", got[0].Name)
+		assert.Equal(t, "IETF Protocol Assignments", got[1].Name)
+		assert.Equal(t, "", got[2].Name)
+	}
+
+	got = s.Difference(networkSet("192.0.0.0/25"))
+	if assert.Len(t, got, 3) {
+		assert.Equal(t, "192.0.0.128/25", got[1].IPNet.String())
+		assert.Equal(t, "IETF Protocol Assignments", got[1].Name)
+	}
+
+	got = s.Intersect(networkSet("192.0.0.0/30"))
+	if assert.Len(t, got, 1) {
+		assert.Equal(t, "IPv4 Service Continuity Prefix", got[0].Name)
+	}
+
+	// Names come from the receiver, whether the networks of the argument are narrower or wider
+	u := NetworkSet{
+		{IPNet: MustParseCIDR("10.64.0.0/10"), Name: "Partial", Category: CategoryCarrierGradeNAT},
+		{IPNet: MustParseCIDR("192.0.0.0/16"), Name: "Wide"},
+	}
+	got = s.Intersect(u)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, []string{"10.64.0.0/10", "192.0.0.0/24"}, cidrStrings(got))
+		assert.Equal(t, "Private-Use", got[0].Name)
+		assert.Equal(t, NetworkCategory(""), got[0].Category)
+		assert.Equal(t, "IETF Protocol Assignments", got[1].Name)
+	}
+
+	got = u.Intersect(s)
+	if assert.Len(t, got, 2) {
+		assert.Equal(t, "Partial", got[0].Name)
+		assert.Equal(t, CategoryCarrierGradeNAT, got[0].Category)
+		assert.Equal(t, "Wide", got[1].Name)
+	}
+}
+
+func TestRangeToCIDRs(t *testing.T) {
+	tests := []struct {
+		first, last string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			first: "192.0.2.0", last: "192.0.2.255",
+			want: []string{"192.0.2.0/24"},
+		},
+		{
+			first: "192.0.2.1", last: "192.0.2.6",
+			want: []string{"192.0.2.1/32", "192.0.2.2/31", "192.0.2.4/31", "192.0.2.6/32"},
+		},
+		{
+			first: "0.0.0.0", last: "255.255.255.255",
+			want: []string{"0.0.0.0/0"},
+		},
+		{
+			first: "10.0.0.0", last: "10.0.0.0",
+			want: []string{"10.0.0.0/32"},
+		},
+		{
+			first: "2001:db8::", last: "2001:db8::ffff:ffff:ffff:ffff:ffff",
+			want: []string{"2001:db8::/48"},
+		},
+		{
+			first: "::", last: "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			want: []string{"::/0"},
+		},
+		{
+			first: "::1", last: "::",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := RangeToCIDRs(net.ParseIP(test.first), net.ParseIP(test.last))
+		if test.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+		if assert.NoError(t, err) {
+			ss := make([]string, len(got))
+			for i, n := range got {
+				ss[i] = n.String()
+			}
+			assert.Equal(t, test.want, ss, "%s - %s", test.first, test.last)
+		}
+	}
+}
+
+func ExampleNetworkSet_Difference() {
+	allowed := NetworkSet{{IPNet: MustParseCIDR("10.0.0.0/9")}}
+	blocklist := NetworkBlocklist{
+		V4: NetworkSet(PrivateNetworkBlocklist.V4).Difference(allowed),
+		V6: PrivateNetworkBlocklist.V6,
+	}
+
+	fmt.Println(blocklist.CheckIP(net.ParseIP("10.1.2.3")))
+	fmt.Println(blocklist.CheckIP(net.ParseIP("10.200.0.1")))
+	// Output:
+	// <nil>
+	// host is blocked (Private-Use)
+}
@@ -0,0 +1,137 @@
+package netutil
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const defaultProxyHeaderTimeout = 10 * time.Second
+
+// BlocklistListener is a net.Listener which closes accepted connections from networks specified in Blocklist.
+//
+// If a connection comes from one of TrustedProxies, its PROXY protocol header is read and
+// the source address in it is checked instead. Since reading the header may block,
+// such connections are returned from Accept before being checked, and closed on their
+// first Read or Write when blocked.
+type BlocklistListener struct {
+	net.Listener
+	Blocklist NetworkBlocklist
+
+	// TrustedProxies are networks of proxies which send PROXY protocol headers.
+	TrustedProxies []*net.IPNet
+	// ProxyHeaderTimeout is the timeout for reading PROXY protocol headers. Defaults to 10 seconds.
+	ProxyHeaderTimeout time.Duration
+
+	// OnReject, if set, is called when a connection is closed by the listener.
+	// err is an ErrBlocked or an error occurred while reading a PROXY protocol header.
+	OnReject func(remoteAddr net.Addr, err error)
+}
+
+// Accept implements net.Listener.
+func (l *BlocklistListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		ip := addrIP(conn.RemoteAddr())
+		if ip == nil {
+			return conn, nil
+		}
+
+		if l.isTrustedProxy(ip) {
+			return &blocklistConn{
+				ProxyConn: newProxyConn(conn, l.ProxyHeaderTimeout, false),
+				listener:  l,
+			}, nil
+		}
+
+		if err := l.Blocklist.CheckIP(ip); err != nil {
+			l.reject(conn, conn.RemoteAddr(), err)
+			continue
+		}
+
+		return conn, nil
+	}
+}
+
+func (l *BlocklistListener) isTrustedProxy(ip net.IP) bool {
+	for _, n := range l.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *BlocklistListener) reject(conn net.Conn, addr net.Addr, err error) {
+	conn.Close()
+	if l.OnReject != nil {
+		l.OnReject(addr, err)
+	}
+}
+
+// blocklistConn is a connection from a trusted proxy, which is checked against blocklist on its first Read or Write.
+type blocklistConn struct {
+	*ProxyConn
+	listener *BlocklistListener
+
+	once sync.Once
+	err  error
+}
+
+func (c *blocklistConn) check() error {
+	c.once.Do(func() {
+		h, err := c.ProxyConn.ProxyHeader()
+		if err != nil {
+			c.err = err
+			c.listener.reject(c.Conn, c.Conn.RemoteAddr(), err)
+			return
+		}
+
+		if ip := addrIP(h.Source); ip != nil {
+			if err := c.listener.Blocklist.CheckIP(ip); err != nil {
+				c.err = err
+				c.listener.reject(c.Conn, h.Source, err)
+			}
+		}
+	})
+	return c.err
+}
+
+func (c *blocklistConn) Read(p []byte) (int, error) {
+	if err := c.check(); err != nil {
+		return 0, err
+	}
+	return c.ProxyConn.Read(p)
+}
+
+func (c *blocklistConn) Write(p []byte) (int, error) {
+	if err := c.check(); err != nil {
+		return 0, err
+	}
+	return c.ProxyConn.Write(p)
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.IPAddr:
+		return addr.IP
+	}
+
+	if addr == nil {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
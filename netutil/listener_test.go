@@ -0,0 +1,140 @@
+package netutil
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func acceptOne(t *testing.T, l net.Listener) <-chan net.Conn {
+	ch := make(chan net.Conn, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(ch)
+			return
+		}
+		ch <- conn
+	}()
+	return ch
+}
+
+func TestBlocklistListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rejected []error
+	l := &BlocklistListener{
+		Listener:  inner,
+		Blocklist: PrivateNetworkBlocklist,
+		OnReject: func(addr net.Addr, err error) {
+			rejected = append(rejected, err)
+		},
+	}
+
+	accepted := acceptOne(t, l)
+
+	conn, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err, "connection should be closed by the listener")
+
+	l.Close()
+	_, ok := <-accepted
+	assert.False(t, ok)
+
+	if assert.Len(t, rejected, 1) {
+		assert.ErrorAs(t, rejected[0], &ErrBlocked{})
+	}
+}
+
+func TestBlocklistListener_TrustedProxies(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		shouldBlock bool
+		remoteAddr  string
+	}{
+		{
+			name:       "public client",
+			header:     "PROXY TCP4 203.0.114.1 10.0.0.1 56324 443\r\n",
+			remoteAddr: "203.0.114.1:56324",
+		},
+		{
+			name:        "private client",
+			header:      "PROXY TCP4 192.168.1.1 10.0.0.1 56324 443\r\n",
+			shouldBlock: true,
+		},
+		{
+			name:        "private client (v2)",
+			header:      "\r\n\r\n\x00\r\nQUIT\n\x21\x21\x00\x24\xfe\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\xdc\x04\x01\xbb",
+			shouldBlock: true,
+		},
+		{
+			name:       "health check",
+			header:     "PROXY UNKNOWN\r\n",
+			remoteAddr: "127.0.0.1",
+		},
+		{
+			name:        "no header",
+			header:      "GET / HTTP/1.1\r\n",
+			shouldBlock: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inner, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var rejected error
+			l := &BlocklistListener{
+				Listener:       inner,
+				Blocklist:      PrivateNetworkBlocklist,
+				TrustedProxies: []*net.IPNet{MustParseCIDR("127.0.0.0/8")},
+				OnReject: func(addr net.Addr, err error) {
+					rejected = err
+				},
+			}
+			defer l.Close()
+
+			accepted := acceptOne(t, l)
+
+			conn, err := net.Dial("tcp", inner.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			io.WriteString(conn, test.header+"hello")
+
+			sconn := <-accepted
+			defer sconn.Close()
+
+			sconn.SetReadDeadline(time.Now().Add(time.Second))
+			b := make([]byte, 5)
+			_, err = io.ReadFull(sconn, b)
+			if test.shouldBlock {
+				assert.Error(t, err)
+				assert.Error(t, rejected)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, rejected)
+				assert.Equal(t, "hello", string(b))
+				assert.Contains(t, sconn.RemoteAddr().String(), test.remoteAddr)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package netutil
+
+import (
+	"net"
+)
+
+// BlocklistPacketConn is a net.PacketConn which refuses to write to networks specified in Blocklist.
+// Unlike NetworkBlocklist.Control, it works with unconnected sockets (eg. those from net.ListenPacket),
+// by checking every destination passed to WriteTo.
+type BlocklistPacketConn struct {
+	net.PacketConn
+	Blocklist NetworkBlocklist
+}
+
+// ListenPacket is like net.ListenPacket but returns a BlocklistPacketConn with l.
+func (l NetworkBlocklist) ListenPacket(network, address string) (*BlocklistPacketConn, error) {
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlocklistPacketConn{
+		PacketConn: conn,
+		Blocklist:  l,
+	}, nil
+}
+
+// WriteTo implements net.PacketConn. It returns a *net.OpError wrapping ErrBlocked when addr is blocked.
+func (c *BlocklistPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if ip := addrIP(addr); ip != nil {
+		if err := c.Blocklist.CheckIP(ip); err != nil {
+			return 0, &net.OpError{
+				Op:     "write",
+				Net:    addr.Network(),
+				Source: c.LocalAddr(),
+				Addr:   addr,
+				Err:    err,
+			}
+		}
+	}
+
+	return c.PacketConn.WriteTo(p, addr)
+}
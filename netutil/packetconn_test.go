@@ -0,0 +1,62 @@
+package netutil
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocklistPacketConn(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer receiver.Close()
+
+	blocklist := NetworkBlocklist{
+		V4: []NamedNetwork{{IPNet: MustParseCIDR("192.0.2.0/24"), Name: "TEST-NET-1"}},
+		V6: []NamedNetwork{{IPNet: MustParseCIDR("2001:db8::/32"), Name: "Documentation"}},
+	}
+
+	conn, err := blocklist.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, addr := range []string{"192.0.2.1:53", "[2001:db8::1]:53", "[::ffff:192.0.2.1]:53"} {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = conn.WriteTo([]byte("x"), udpAddr)
+		assert.ErrorAs(t, err, &ErrBlocked{}, addr)
+	}
+
+	_, err = conn.WriteTo([]byte("hello"), receiver.LocalAddr())
+	assert.NoError(t, err)
+
+	receiver.SetReadDeadline(time.Now().Add(time.Second))
+	b := make([]byte, 16)
+	n, _, err := receiver.ReadFrom(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b[:n]))
+}
+
+func TestBlocklistPacketConn_Private(t *testing.T) {
+	conn, err := PrivateNetworkBlocklist.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = conn.WriteTo([]byte("x"), &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53})
+	assert.EqualError(t, err, "write udp 127.0.0.1:"+portOf(conn.LocalAddr())+"->127.0.0.1:53: host is blocked (Loopback)")
+}
+
+func portOf(addr net.Addr) string {
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
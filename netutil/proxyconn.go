@@ -0,0 +1,181 @@
+package netutil
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// ProxyListener is a net.Listener which accepts connections starting with PROXY protocol headers.
+// Accepted connections are *ProxyConn.
+type ProxyListener struct {
+	net.Listener
+
+	// HeaderTimeout is the timeout for reading PROXY protocol headers. Defaults to 10 seconds.
+	HeaderTimeout time.Duration
+	// Optional allows connections without PROXY protocol headers.
+	Optional bool
+}
+
+// Accept implements net.Listener.
+func (l *ProxyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newProxyConn(conn, l.HeaderTimeout, l.Optional), nil
+}
+
+// ProxyConn is a connection which starts with a PROXY protocol header.
+// The header is read lazily on the first Read, RemoteAddr or LocalAddr, so that Accept does not block.
+type ProxyConn struct {
+	net.Conn
+	r             *bufio.Reader
+	headerTimeout time.Duration
+	optional      bool
+
+	once   sync.Once
+	header *ProxyHeader
+	err    error
+
+	mu           sync.Mutex
+	readDeadline time.Time
+}
+
+func newProxyConn(conn net.Conn, headerTimeout time.Duration, optional bool) *ProxyConn {
+	if headerTimeout == 0 {
+		headerTimeout = defaultProxyHeaderTimeout
+	}
+	return &ProxyConn{
+		Conn:          conn,
+		r:             bufio.NewReader(conn),
+		headerTimeout: headerTimeout,
+		optional:      optional,
+	}
+}
+
+func (c *ProxyConn) readHeader() {
+	c.once.Do(func() {
+		c.mu.Lock()
+		deadline := time.Now().Add(c.headerTimeout)
+		if !c.readDeadline.IsZero() && c.readDeadline.Before(deadline) {
+			deadline = c.readDeadline
+		}
+		c.Conn.SetReadDeadline(deadline)
+		c.mu.Unlock()
+
+		c.header, c.err = ReadProxyHeader(c.r)
+		if c.err == ErrNoProxyHeader && c.optional {
+			c.err = nil
+		}
+
+		c.mu.Lock()
+		c.Conn.SetReadDeadline(c.readDeadline)
+		c.mu.Unlock()
+	})
+}
+
+// ProxyHeader returns the PROXY protocol header of c, reading it if not yet.
+// The header is nil if there is no header and the listener is Optional.
+func (c *ProxyConn) ProxyHeader() (*ProxyHeader, error) {
+	c.readHeader()
+	return c.header, c.err
+}
+
+// Read implements net.Conn. It returns the error occurred while reading the header if any.
+func (c *ProxyConn) Read(p []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(p)
+}
+
+// RemoteAddr returns the source address in the header if any.
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.header != nil && c.header.Source != nil {
+		return c.header.Source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address in the header if any.
+func (c *ProxyConn) LocalAddr() net.Addr {
+	c.readHeader()
+	if c.header != nil && c.header.Destination != nil {
+		return c.header.Destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *ProxyConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *ProxyConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+type proxyHeaderContextKey struct{}
+
+// WithProxyHeader returns a context which makes ProxyDialer send h.
+func WithProxyHeader(ctx context.Context, h *ProxyHeader) context.Context {
+	return context.WithValue(ctx, proxyHeaderContextKey{}, h)
+}
+
+// ProxyDialer dials connections and sends PROXY protocol headers on them.
+// The header is taken from the context passed to DialContext (see WithProxyHeader).
+// If the context has no header, a LOCAL header of Version is sent.
+type ProxyDialer struct {
+	Dialer  *net.Dialer
+	Version int // defaults to 2
+}
+
+// Dial is like DialContext with context.Background().
+func (d *ProxyDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext dials address and writes a PROXY protocol header to the connection.
+func (d *ProxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	h, _ := ctx.Value(proxyHeaderContextKey{}).(*ProxyHeader)
+	if h == nil {
+		version := d.Version
+		if version == 0 {
+			version = 2
+		}
+		h = &ProxyHeader{Version: version, Command: ProxyCommandLocal}
+	}
+
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+		defer conn.SetWriteDeadline(time.Time{})
+	}
+
+	if _, err := h.WriteTo(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
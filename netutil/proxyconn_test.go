@@ -0,0 +1,144 @@
+package netutil
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &ProxyListener{Listener: inner}
+	defer l.Close()
+
+	accepted := acceptOne(t, l)
+
+	src := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443}
+	ctx := WithProxyHeader(context.Background(), &ProxyHeader{
+		Version:     2,
+		Command:     ProxyCommandProxy,
+		Source:      src,
+		Destination: dst,
+		TLVs:        []ProxyTLV{{Type: ProxyTLVTypeAuthority, Value: []byte("example.com")}},
+	})
+
+	d := &ProxyDialer{}
+	conn, err := d.DialContext(ctx, "tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "hello")
+	conn.Close()
+
+	sconn := <-accepted
+	defer sconn.Close()
+
+	assert.Equal(t, src.String(), sconn.RemoteAddr().String())
+	assert.Equal(t, dst.String(), sconn.LocalAddr().String())
+
+	h, err := sconn.(*ProxyConn).ProxyHeader()
+	if assert.NoError(t, err) {
+		authority, _ := h.TLV(ProxyTLVTypeAuthority)
+		assert.Equal(t, "example.com", string(authority))
+	}
+
+	b, err := ioutil.ReadAll(sconn)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestProxyListener_Optional(t *testing.T) {
+	for _, optional := range []bool{true, false} {
+		inner, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := &ProxyListener{Listener: inner, Optional: optional}
+
+		accepted := acceptOne(t, l)
+
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(conn, "hello")
+		conn.Close()
+
+		sconn := <-accepted
+		b, err := ioutil.ReadAll(sconn)
+		if optional {
+			assert.NoError(t, err)
+			assert.Equal(t, "hello", string(b))
+			assert.Equal(t, conn.LocalAddr().String(), sconn.RemoteAddr().String())
+		} else {
+			assert.ErrorIs(t, err, ErrNoProxyHeader)
+		}
+
+		sconn.Close()
+		l.Close()
+	}
+}
+
+func TestProxyListener_HeaderTimeout(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &ProxyListener{Listener: inner, HeaderTimeout: 50 * time.Millisecond}
+	defer l.Close()
+
+	accepted := acceptOne(t, l)
+
+	conn, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "PROXY TCP4 ")
+
+	sconn := <-accepted
+	defer sconn.Close()
+
+	_, err = sconn.Read(make([]byte, 1))
+	var netErr net.Error
+	if assert.ErrorAs(t, err, &netErr) {
+		assert.True(t, netErr.Timeout())
+	}
+}
+
+func TestProxyDialer_Local(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &ProxyListener{Listener: inner}
+	defer l.Close()
+
+	accepted := acceptOne(t, l)
+
+	d := &ProxyDialer{Version: 1}
+	conn, err := d.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sconn := <-accepted
+	defer sconn.Close()
+
+	h, err := sconn.(*ProxyConn).ProxyHeader()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, h.Version)
+		assert.Equal(t, ProxyCommandLocal, h.Command)
+	}
+	assert.Equal(t, conn.LocalAddr().String(), sconn.RemoteAddr().String())
+}
@@ -0,0 +1,497 @@
+package netutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// https://www.haproxy.org/download/2.8/doc/proxy-protocol.txt
+
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const proxyV1MaxLength = 107
+
+// ErrNoProxyHeader is returned by ReadProxyHeader when the stream does not start with a PROXY protocol header.
+var ErrNoProxyHeader = errors.New("no PROXY protocol header")
+
+// ProxyCommand is the command of a PROXY protocol header.
+type ProxyCommand byte
+
+const (
+	// ProxyCommandLocal means the connection was established by the proxy itself (eg. health checks).
+	// Source and Destination are nil for this command.
+	ProxyCommandLocal ProxyCommand = 0x0
+	// ProxyCommandProxy means the connection was relayed on behalf of another node.
+	ProxyCommandProxy ProxyCommand = 0x1
+)
+
+// ProxyTLVType is the type of a PROXY protocol version 2 TLV.
+type ProxyTLVType byte
+
+const (
+	ProxyTLVTypeALPN      ProxyTLVType = 0x01
+	ProxyTLVTypeAuthority ProxyTLVType = 0x02
+	ProxyTLVTypeCRC32C    ProxyTLVType = 0x03
+	ProxyTLVTypeNoop      ProxyTLVType = 0x04
+	ProxyTLVTypeUniqueID  ProxyTLVType = 0x05
+	ProxyTLVTypeSSL       ProxyTLVType = 0x20
+	ProxyTLVTypeNetNS     ProxyTLVType = 0x30
+)
+
+// ProxyTLV is a TLV (type-length-value) vector in a PROXY protocol version 2 header.
+type ProxyTLV struct {
+	Type  ProxyTLVType
+	Value []byte
+}
+
+// ProxyHeader represents a PROXY protocol header.
+type ProxyHeader struct {
+	Version     int
+	Command     ProxyCommand
+	Source      net.Addr
+	Destination net.Addr
+	TLVs        []ProxyTLV // only for version 2
+}
+
+// NewProxyHeader returns a header which relays conn, that is, its Source is conn.RemoteAddr()
+// and Destination is conn.LocalAddr().
+func NewProxyHeader(version int, conn net.Conn) *ProxyHeader {
+	return &ProxyHeader{
+		Version:     version,
+		Command:     ProxyCommandProxy,
+		Source:      conn.RemoteAddr(),
+		Destination: conn.LocalAddr(),
+	}
+}
+
+// TLV returns the value of the first TLV of type typ in h.
+func (h *ProxyHeader) TLV(typ ProxyTLVType) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == typ {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ReadProxyHeader reads a PROXY protocol version 1 or 2 header from r.
+// If r does not start with a header, ErrNoProxyHeader is returned and nothing is consumed from r.
+func ReadProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	b, err := r.Peek(len(proxyV1Prefix))
+	if err != nil {
+		if err == io.EOF && len(b) == 0 {
+			return nil, err
+		}
+		if !bytes.HasPrefix(proxyV1Prefix, b) && !bytes.HasPrefix(proxyV2Signature, b) {
+			return nil, ErrNoProxyHeader
+		}
+		return nil, fmt.Errorf("reading PROXY header: %w", err)
+	}
+
+	if bytes.Equal(b, proxyV1Prefix) {
+		return readProxyHeaderV1(r)
+	}
+
+	if bytes.HasPrefix(proxyV2Signature, b) {
+		b, err = r.Peek(len(proxyV2Signature))
+		if bytes.Equal(b, proxyV2Signature) {
+			return readProxyHeaderV2(r)
+		}
+		if err != nil && bytes.HasPrefix(proxyV2Signature, b) {
+			return nil, fmt.Errorf("reading PROXY header: %w", err)
+		}
+	}
+
+	return nil, ErrNoProxyHeader
+}
+
+func readProxyHeaderV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("reading PROXY v1 header: %w", err)
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+		if len(line) >= proxyV1MaxLength {
+			return nil, fmt.Errorf("PROXY v1 header too long")
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("PROXY v1 header not terminated by CRLF")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("malformed PROXY v1 header %q", line)
+	}
+
+	h := &ProxyHeader{Version: 1, Command: ProxyCommandProxy}
+
+	switch fields[1] {
+	case "UNKNOWN":
+		// The receiver must ignore anything presented after UNKNOWN.
+		h.Command = ProxyCommandLocal
+		return h, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("unknown PROXY v1 protocol %q", fields[1])
+	}
+
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("malformed PROXY v1 header %q", line)
+	}
+
+	src, err := parseProxyV1Addr(fields[1], fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	dst, err := parseProxyV1Addr(fields[1], fields[3], fields[5])
+	if err != nil {
+		return nil, err
+	}
+
+	h.Source, h.Destination = src, dst
+
+	return h, nil
+}
+
+func parseProxyV1Addr(proto, host, port string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("cannot parse PROXY v1 address %q", host)
+	}
+	if (proto == "TCP4") != (ip.To4() != nil && !strings.Contains(host, ":")) {
+		return nil, fmt.Errorf("PROXY v1 address %q does not match protocol %s", host, proto)
+	}
+
+	if len(port) > 1 && port[0] == '0' {
+		return nil, fmt.Errorf("cannot parse PROXY v1 port %q", port)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse PROXY v1 port %q", port)
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+const (
+	proxyV2FamilyUnspec = 0x0
+	proxyV2FamilyInet   = 0x1
+	proxyV2FamilyInet6  = 0x2
+	proxyV2FamilyUnix   = 0x3
+
+	proxyV2TransportUnspec = 0x0
+	proxyV2TransportStream = 0x1
+	proxyV2TransportDgram  = 0x2
+)
+
+func readProxyHeaderV2(r *bufio.Reader) (*ProxyHeader, error) {
+	raw := make([]byte, 16)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, fmt.Errorf("reading PROXY v2 header: %w", err)
+	}
+
+	if version := raw[12] >> 4; version != 2 {
+		return nil, fmt.Errorf("unknown PROXY v2 version %d", version)
+	}
+
+	h := &ProxyHeader{
+		Version: 2,
+		Command: ProxyCommand(raw[12] & 0x0F),
+	}
+	if h.Command != ProxyCommandLocal && h.Command != ProxyCommandProxy {
+		return nil, fmt.Errorf("unknown PROXY v2 command %#x", h.Command)
+	}
+
+	family, transport := raw[13]>>4, raw[13]&0x0F
+
+	raw = append(raw, make([]byte, binary.BigEndian.Uint16(raw[14:16]))...)
+	if _, err := io.ReadFull(r, raw[16:]); err != nil {
+		return nil, fmt.Errorf("reading PROXY v2 header: %w", err)
+	}
+
+	// The receiver must ignore the protocol block for LOCAL command.
+	if h.Command == ProxyCommandLocal {
+		return h, nil
+	}
+
+	src, dst, rest, err := parseProxyV2Addrs(family, transport, raw[16:])
+	if err != nil {
+		return nil, err
+	}
+
+	h.TLVs, err = parseProxyV2TLVs(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyProxyV2Checksum(raw, len(raw)-len(rest), h.TLVs); err != nil {
+		return nil, err
+	}
+
+	h.Source, h.Destination = src, dst
+
+	return h, nil
+}
+
+func parseProxyV2TLVs(b []byte) ([]ProxyTLV, error) {
+	var tlvs []ProxyTLV
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, fmt.Errorf("malformed PROXY v2 TLV")
+		}
+		typ, length := ProxyTLVType(b[0]), int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < 3+length {
+			return nil, fmt.Errorf("malformed PROXY v2 TLV (type %#x)", typ)
+		}
+		tlvs = append(tlvs, ProxyTLV{
+			Type:  typ,
+			Value: b[3 : 3+length],
+		})
+		b = b[3+length:]
+	}
+	return tlvs, nil
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// verifyProxyV2Checksum verifies the CRC32C TLV if any. tlvStart is the offset of the TLVs in raw.
+func verifyProxyV2Checksum(raw []byte, tlvStart int, tlvs []ProxyTLV) error {
+	b := raw[tlvStart:]
+	for _, tlv := range tlvs {
+		if tlv.Type != ProxyTLVTypeCRC32C {
+			b = b[3+len(tlv.Value):]
+			continue
+		}
+
+		if len(tlv.Value) != 4 {
+			return fmt.Errorf("malformed PROXY v2 CRC32C TLV")
+		}
+
+		expected := binary.BigEndian.Uint32(tlv.Value)
+
+		zeroed := append([]byte(nil), raw...)
+		offset := len(raw) - len(b) + 3
+		copy(zeroed[offset:offset+4], []byte{0, 0, 0, 0})
+		if actual := crc32.Checksum(zeroed, crc32cTable); actual != expected {
+			return fmt.Errorf("PROXY v2 checksum mismatch: expected %#08x but got %#08x", expected, actual)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// parseProxyV2Addrs parses address block of PROXY v2 header and returns the remaining bytes.
+func parseProxyV2Addrs(family, transport byte, b []byte) (src, dst net.Addr, rest []byte, err error) {
+	var size int
+	switch family {
+	case proxyV2FamilyUnspec:
+		return nil, nil, nil, nil
+	case proxyV2FamilyInet:
+		size = 2*net.IPv4len + 4
+	case proxyV2FamilyInet6:
+		size = 2*net.IPv6len + 4
+	case proxyV2FamilyUnix:
+		size = 2 * 108
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown PROXY v2 address family %#x", family)
+	}
+
+	if transport != proxyV2TransportStream && transport != proxyV2TransportDgram {
+		if transport == proxyV2TransportUnspec {
+			return nil, nil, nil, nil
+		}
+		return nil, nil, nil, fmt.Errorf("unknown PROXY v2 transport protocol %#x", transport)
+	}
+
+	if len(b) < size {
+		return nil, nil, nil, fmt.Errorf("PROXY v2 address block too short: %d bytes", len(b))
+	}
+
+	if family == proxyV2FamilyUnix {
+		network := "unix"
+		if transport == proxyV2TransportDgram {
+			network = "unixgram"
+		}
+		src = &net.UnixAddr{Net: network, Name: string(bytes.TrimRight(b[:108], "\x00"))}
+		dst = &net.UnixAddr{Net: network, Name: string(bytes.TrimRight(b[108:216], "\x00"))}
+		return src, dst, b[size:], nil
+	}
+
+	ipLen := (size - 4) / 2
+	srcIP := net.IP(append([]byte(nil), b[:ipLen]...))
+	dstIP := net.IP(append([]byte(nil), b[ipLen:2*ipLen]...))
+	srcPort := int(binary.BigEndian.Uint16(b[2*ipLen:]))
+	dstPort := int(binary.BigEndian.Uint16(b[2*ipLen+2:]))
+
+	if transport == proxyV2TransportDgram {
+		src = &net.UDPAddr{IP: srcIP, Port: srcPort}
+		dst = &net.UDPAddr{IP: dstIP, Port: dstPort}
+	} else {
+		src = &net.TCPAddr{IP: srcIP, Port: srcPort}
+		dst = &net.TCPAddr{IP: dstIP, Port: dstPort}
+	}
+
+	return src, dst, b[size:], nil
+}
+
+// WriteTo writes h to w in the format of h.Version.
+func (h *ProxyHeader) WriteTo(w io.Writer) (int64, error) {
+	var b []byte
+	var err error
+	switch h.Version {
+	case 1:
+		b, err = h.formatV1()
+	case 2:
+		b, err = h.formatV2()
+	default:
+		err = fmt.Errorf("unknown PROXY protocol version %d", h.Version)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+func (h *ProxyHeader) formatV1() ([]byte, error) {
+	if h.Command == ProxyCommandLocal || h.Source == nil || h.Destination == nil {
+		return []byte("PROXY UNKNOWN\r\n"), nil
+	}
+
+	src, ok1 := h.Source.(*net.TCPAddr)
+	dst, ok2 := h.Destination.(*net.TCPAddr)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("PROXY v1 supports only TCP addresses: %s -> %s", h.Source, h.Destination)
+	}
+
+	proto := "TCP6"
+	srcIP, dstIP := src.IP.String(), dst.IP.String()
+	if src.IP.To4() != nil && dst.IP.To4() != nil {
+		proto = "TCP4"
+		srcIP, dstIP = src.IP.To4().String(), dst.IP.To4().String()
+	} else if src.IP.To4() != nil || dst.IP.To4() != nil {
+		// net.IP.String() renders IPv4-mapped addresses in dotted form
+		srcIP, dstIP = formatIPv6(src.IP), formatIPv6(dst.IP)
+	}
+
+	return []byte(fmt.Sprintf("PROXY %s %s %s %d %d\r\n", proto, srcIP, dstIP, src.Port, dst.Port)), nil
+}
+
+func formatIPv6(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return "::ffff:" + ip4.String()
+	}
+	return ip.String()
+}
+
+func (h *ProxyHeader) formatV2() ([]byte, error) {
+	b := append([]byte(nil), proxyV2Signature...)
+	b = append(b, 0x20|byte(h.Command), 0, 0, 0)
+
+	if h.Command == ProxyCommandProxy && h.Source != nil && h.Destination != nil {
+		famTrans, addrs, err := formatProxyV2Addrs(h.Source, h.Destination)
+		if err != nil {
+			return nil, err
+		}
+		b[13] = famTrans
+		b = append(b, addrs...)
+	}
+
+	crcOffset := -1
+	for _, tlv := range h.TLVs {
+		if len(tlv.Value) > 0xFFFF {
+			return nil, fmt.Errorf("PROXY v2 TLV too long (type %#x)", tlv.Type)
+		}
+		value := tlv.Value
+		if tlv.Type == ProxyTLVTypeCRC32C && crcOffset == -1 {
+			// filled after the whole header is built
+			value = []byte{0, 0, 0, 0}
+			crcOffset = len(b) + 3
+		}
+		b = append(b, byte(tlv.Type), 0, 0)
+		binary.BigEndian.PutUint16(b[len(b)-2:], uint16(len(value)))
+		b = append(b, value...)
+	}
+
+	if len(b)-16 > 0xFFFF {
+		return nil, fmt.Errorf("PROXY v2 header too long")
+	}
+	binary.BigEndian.PutUint16(b[14:16], uint16(len(b)-16))
+
+	if crcOffset != -1 {
+		binary.BigEndian.PutUint32(b[crcOffset:], crc32.Checksum(b, crc32cTable))
+	}
+
+	return b, nil
+}
+
+func formatProxyV2Addrs(src, dst net.Addr) (byte, []byte, error) {
+	switch src := src.(type) {
+	case *net.UnixAddr:
+		dst, ok := dst.(*net.UnixAddr)
+		if !ok {
+			break
+		}
+		transport := byte(proxyV2TransportStream)
+		if src.Net == "unixgram" {
+			transport = proxyV2TransportDgram
+		}
+		b := make([]byte, 2*108)
+		copy(b[:108], src.Name)
+		copy(b[108:], dst.Name)
+		return proxyV2FamilyUnix<<4 | transport, b, nil
+
+	case *net.TCPAddr:
+		if dst, ok := dst.(*net.TCPAddr); ok {
+			return formatProxyV2IPAddrs(proxyV2TransportStream, src.IP, dst.IP, src.Port, dst.Port)
+		}
+
+	case *net.UDPAddr:
+		if dst, ok := dst.(*net.UDPAddr); ok {
+			return formatProxyV2IPAddrs(proxyV2TransportDgram, src.IP, dst.IP, src.Port, dst.Port)
+		}
+	}
+
+	return 0, nil, fmt.Errorf("unsupported PROXY v2 addresses: %s -> %s", src, dst)
+}
+
+func formatProxyV2IPAddrs(transport byte, srcIP, dstIP net.IP, srcPort, dstPort int) (byte, []byte, error) {
+	family := byte(proxyV2FamilyInet6)
+	if srcIP.To4() != nil && dstIP.To4() != nil {
+		family = proxyV2FamilyInet
+		srcIP, dstIP = srcIP.To4(), dstIP.To4()
+	} else {
+		srcIP, dstIP = srcIP.To16(), dstIP.To16()
+	}
+	if srcIP == nil || dstIP == nil {
+		return 0, nil, fmt.Errorf("invalid IP address: %s -> %s", srcIP, dstIP)
+	}
+
+	b := append(append([]byte(nil), srcIP...), dstIP...)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-4:], uint16(srcPort))
+	binary.BigEndian.PutUint16(b[len(b)-2:], uint16(dstPort))
+
+	return family<<4 | transport, b, nil
+}
@@ -0,0 +1,248 @@
+package netutil
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    *ProxyHeader
+		wantSrc string
+		wantDst string
+		wantErr bool
+	}{
+		{
+			name:    "v1 TCP4",
+			in:      "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\nrest",
+			want:    &ProxyHeader{Version: 1, Command: ProxyCommandProxy},
+			wantSrc: "192.0.2.1:56324",
+			wantDst: "198.51.100.1:443",
+		},
+		{
+			name:    "v1 TCP6",
+			in:      "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\nrest",
+			want:    &ProxyHeader{Version: 1, Command: ProxyCommandProxy},
+			wantSrc: "[2001:db8::1]:56324",
+			wantDst: "[2001:db8::2]:443",
+		},
+		{
+			name: "v1 UNKNOWN",
+			in:   "PROXY UNKNOWN ffff:f...f:ffff ffff:f...f:ffff 65535 65535\r\nrest",
+			want: &ProxyHeader{Version: 1, Command: ProxyCommandLocal},
+		},
+		{
+			name:    "v1 protocol mismatch",
+			in:      "PROXY TCP6 192.0.2.1 198.51.100.1 56324 443\r\nrest",
+			wantErr: true,
+		},
+		{
+			name:    "v1 without CRLF",
+			in:      "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\nrest",
+			wantErr: true,
+		},
+		{
+			name:    "v1 too long",
+			in:      "PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "v1 bad port",
+			in:      "PROXY TCP4 192.0.2.1 198.51.100.1 065536 443\r\nrest",
+			wantErr: true,
+		},
+		{
+			name:    "v2 TCP4",
+			in:      "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c\xc0\x00\x02\x01\xc6\x33\x64\x01\xdc\x04\x01\xbbrest",
+			want:    &ProxyHeader{Version: 2, Command: ProxyCommandProxy},
+			wantSrc: "192.0.2.1:56324",
+			wantDst: "198.51.100.1:443",
+		},
+		{
+			name:    "v2 UDP4",
+			in:      "\r\n\r\n\x00\r\nQUIT\n\x21\x12\x00\x0c\xc0\x00\x02\x01\xc6\x33\x64\x01\x00\x35\x00\x35rest",
+			want:    &ProxyHeader{Version: 2, Command: ProxyCommandProxy},
+			wantSrc: "192.0.2.1:53",
+			wantDst: "198.51.100.1:53",
+		},
+		{
+			name: "v2 LOCAL",
+			in:   "\r\n\r\n\x00\r\nQUIT\n\x20\x00\x00\x00rest",
+			want: &ProxyHeader{Version: 2, Command: ProxyCommandLocal},
+		},
+		{
+			name:    "v2 truncated",
+			in:      "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c\xc0\x00\x02\x01",
+			wantErr: true,
+		},
+		{
+			name:    "v2 bad version",
+			in:      "\r\n\r\n\x00\r\nQUIT\n\x11\x11\x00\x0c\xc0\x00\x02\x01\xc6\x33\x64\x01\xdc\x04\x01\xbbrest",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(test.in))
+			h, err := ReadProxyHeader(r)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, test.want.Version, h.Version)
+			assert.Equal(t, test.want.Command, h.Command)
+			if test.wantSrc != "" {
+				assert.Equal(t, test.wantSrc, h.Source.String())
+				assert.Equal(t, test.wantDst, h.Destination.String())
+			} else {
+				assert.Nil(t, h.Source)
+				assert.Nil(t, h.Destination)
+			}
+
+			rest, _ := ioutil.ReadAll(r)
+			assert.Equal(t, "rest", string(rest))
+		})
+	}
+}
+
+func TestReadProxyHeader_NoHeader(t *testing.T) {
+	for _, in := range []string{"GET / HTTP/1.1\r\n", "PRO", "\r\n\r\nfoo"} {
+		r := bufio.NewReader(strings.NewReader(in))
+		_, err := ReadProxyHeader(r)
+		if in == "PRO" {
+			assert.Error(t, err)
+			continue
+		}
+		assert.ErrorIs(t, err, ErrNoProxyHeader)
+
+		rest, _ := ioutil.ReadAll(r)
+		assert.Equal(t, in, string(rest))
+	}
+}
+
+func TestProxyHeader_WriteTo(t *testing.T) {
+	tcp4 := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324}
+	tcp4d := &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443}
+	tcp6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 56324}
+	udp6 := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53}
+	udp6d := &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 53}
+	unix := &net.UnixAddr{Net: "unix", Name: "/tmp/src.sock"}
+	unixd := &net.UnixAddr{Net: "unix", Name: "/tmp/dst.sock"}
+
+	tests := []struct {
+		name   string
+		header ProxyHeader
+		wantV1 string
+	}{
+		{
+			name:   "TCP4",
+			header: ProxyHeader{Command: ProxyCommandProxy, Source: tcp4, Destination: tcp4d},
+			wantV1: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n",
+		},
+		{
+			name:   "TCP6 mixed",
+			header: ProxyHeader{Command: ProxyCommandProxy, Source: tcp6, Destination: tcp4d},
+			wantV1: "PROXY TCP6 2001:db8::1 ::ffff:198.51.100.1 56324 443\r\n",
+		},
+		{
+			name:   "LOCAL",
+			header: ProxyHeader{Command: ProxyCommandLocal},
+			wantV1: "PROXY UNKNOWN\r\n",
+		},
+		{
+			name:   "UDP6",
+			header: ProxyHeader{Command: ProxyCommandProxy, Source: udp6, Destination: udp6d},
+		},
+		{
+			name:   "unix",
+			header: ProxyHeader{Command: ProxyCommandProxy, Source: unix, Destination: unixd},
+		},
+		{
+			name: "TLVs",
+			header: ProxyHeader{
+				Command:     ProxyCommandProxy,
+				Source:      tcp4,
+				Destination: tcp4d,
+				TLVs: []ProxyTLV{
+					{Type: ProxyTLVTypeAuthority, Value: []byte("example.com")},
+					{Type: ProxyTLVTypeCRC32C},
+					{Type: ProxyTLVTypeUniqueID, Value: []byte("xyz")},
+				},
+			},
+			wantV1: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, version := range []int{1, 2} {
+				h := test.header
+				h.Version = version
+
+				var buf bytes.Buffer
+				_, err := h.WriteTo(&buf)
+				if version == 1 && test.wantV1 == "" {
+					assert.Error(t, err)
+					continue
+				}
+				if !assert.NoError(t, err) {
+					continue
+				}
+				if version == 1 {
+					assert.Equal(t, test.wantV1, buf.String())
+				}
+
+				got, err := ReadProxyHeader(bufio.NewReader(&buf))
+				if !assert.NoError(t, err) {
+					continue
+				}
+				assert.Equal(t, version, got.Version)
+				assert.Equal(t, h.Command, got.Command)
+				if h.Source != nil {
+					assert.Equal(t, h.Source.String(), got.Source.String())
+					assert.Equal(t, h.Destination.String(), got.Destination.String())
+				}
+				if version == 2 && h.TLVs != nil {
+					v, ok := got.TLV(ProxyTLVTypeAuthority)
+					assert.True(t, ok)
+					assert.Equal(t, "example.com", string(v))
+					v, ok = got.TLV(ProxyTLVTypeUniqueID)
+					assert.True(t, ok)
+					assert.Equal(t, "xyz", string(v))
+				}
+			}
+		})
+	}
+}
+
+func TestReadProxyHeader_Checksum(t *testing.T) {
+	h := &ProxyHeader{
+		Version:     2,
+		Command:     ProxyCommandProxy,
+		Source:      &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 56324},
+		Destination: &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 443},
+		TLVs:        []ProxyTLV{{Type: ProxyTLVTypeCRC32C}},
+	}
+
+	var buf bytes.Buffer
+	h.WriteTo(&buf)
+
+	b := buf.Bytes()
+	b[len(b)-1] ^= 0xFF
+
+	_, err := ReadProxyHeader(bufio.NewReader(bytes.NewReader(b)))
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package urlutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+// whatwgTestCase is a test case in testdata/urltestdata.json, which is from web-platform-tests.
+// https://github.com/web-platform-tests/wpt/blob/master/url/resources/urltestdata.json
+type whatwgTestCase struct {
+	Input    string  `json:"input"`
+	Base     *string `json:"base"`
+	Href     string  `json:"href"`
+	Hostname string  `json:"hostname"`
+	Failure  bool    `json:"failure"`
+}
+
+func (tc whatwgTestCase) String() string {
+	if tc.Base != nil {
+		return fmt.Sprintf("<%s> against <%s>", tc.Input, *tc.Base)
+	}
+	return fmt.Sprintf("<%s>", tc.Input)
+}
+
+// loadJSONTestCases loads test cases from a JSON array in filename into v, skipping string entries as comments.
+func loadJSONTestCases(t *testing.T, filename string, v interface{}) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := make([]json.RawMessage, 0, len(entries))
+	for _, e := range entries {
+		if e[0] == '{' {
+			objects = append(objects, e)
+		}
+	}
+
+	b, err = json.Marshal(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// loadExpectedFailures loads a list of test cases on which NormalizeURL is known to
+// deviate from the corpus, in the format of String() of the test cases, one per line.
+func loadExpectedFailures(t *testing.T, filename string) map[string]bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	failures := map[string]bool{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		failures[line] = true
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return failures
+}
+
+// minWHATWGCases and minSafeBrowsingCases are the minimum numbers of test cases exercised, that is,
+// neither skipped nor expected to fail, so that changes of the corpora or the filters do not
+// silently reduce the coverage.
+const (
+	minWHATWGCases       = 121
+	minSafeBrowsingCases = 15
+)
+
+// TestNormalizeURL_WHATWG checks that an input URL and its serialization by the WHATWG URL parser
+// are normalized into the same URL, for valid absolute http(s) URLs in the corpus.
+func TestNormalizeURL_WHATWG(t *testing.T) {
+	failures := loadExpectedFailures(t, "testdata/urltestdata_expected_failures.txt")
+
+	var cases []whatwgTestCase
+	loadJSONTestCases(t, "testdata/urltestdata.json", &cases)
+
+	var exercised, expectedFailures, skipped int
+	for _, tc := range cases {
+		if tc.Failure || !strings.HasPrefix(tc.Href, "http://") && !strings.HasPrefix(tc.Href, "https://") {
+			continue
+		}
+
+		u, err := url.Parse(tc.Input)
+		if err != nil || u.Opaque != "" || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			// inputs which WHATWG parser recovers from, or relative references
+			skipped++
+			continue
+		}
+
+		expectFailure := failures[tc.String()]
+		if expectFailure {
+			expectedFailures++
+		} else {
+			exercised++
+		}
+
+		got, err := NormalizeURL(u)
+		var want *url.URL
+		if err == nil {
+			want, err = url.Parse(tc.Href)
+			if err == nil {
+				want, err = NormalizeURL(want)
+			}
+		}
+
+		ok := err == nil && got.String() == want.String() && got.Hostname() == strings.Trim(tc.Hostname, "[]")
+		if ok && expectFailure {
+			t.Errorf("%s: expected to fail but passed; remove it from expected failures", tc)
+		} else if !ok && !expectFailure {
+			if err != nil {
+				t.Errorf("%s: error: %v", tc, err)
+			} else {
+				t.Errorf("%s: NormalizeURL() = <%s>, normalized href = <%s>, want hostname %q", tc, got, want, tc.Hostname)
+			}
+		}
+	}
+
+	t.Logf("%d cases exercised, %d expected to fail, %d skipped", exercised, expectedFailures, skipped)
+	if expectedFailures != len(failures) {
+		t.Errorf("%d of %d expected failures do not match exercised cases", len(failures)-expectedFailures, len(failures))
+	}
+	if exercised < minWHATWGCases {
+		t.Errorf("only %d cases exercised, want at least %d", exercised, minWHATWGCases)
+	}
+}
+
+// safeBrowsingTestCase is a test case in testdata/safebrowsing.json.
+type safeBrowsingTestCase struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+func (tc safeBrowsingTestCase) String() string {
+	return fmt.Sprintf("<%s>", tc.Input)
+}
+
+// TestNormalizeURL_SafeBrowsing checks that an input URL and its canonicalization for Google Safe Browsing
+// are normalized into the same URL. Safe Browsing discards fragments, so do we here.
+func TestNormalizeURL_SafeBrowsing(t *testing.T) {
+	failures := loadExpectedFailures(t, "testdata/safebrowsing_expected_failures.txt")
+
+	var cases []safeBrowsingTestCase
+	loadJSONTestCases(t, "testdata/safebrowsing.json", &cases)
+
+	opts := NormalizeOptions{RemoveFragment: true}
+
+	var exercised, expectedFailures, skipped int
+	for _, tc := range cases {
+		u, err := url.Parse(tc.Input)
+		if err != nil || u.Host == "" {
+			// inputs which Safe Browsing recovers from
+			skipped++
+			continue
+		}
+
+		expectFailure := failures[tc.String()]
+		if expectFailure {
+			expectedFailures++
+		} else {
+			exercised++
+		}
+
+		got, err := opts.Normalize(u)
+		var want *url.URL
+		if err == nil {
+			want, err = url.Parse(tc.Output)
+			if err == nil {
+				want, err = opts.Normalize(want)
+			}
+		}
+
+		ok := err == nil && got.String() == want.String()
+		if ok && expectFailure {
+			t.Errorf("%s: expected to fail but passed; remove it from expected failures", tc)
+		} else if !ok && !expectFailure {
+			if err != nil {
+				t.Errorf("%s: error: %v", tc, err)
+			} else {
+				t.Errorf("%s: Normalize() = <%s>, normalized output = <%s>", tc, got, want)
+			}
+		}
+	}
+
+	t.Logf("%d cases exercised, %d expected to fail, %d skipped", exercised, expectedFailures, skipped)
+	if expectedFailures != len(failures) {
+		t.Errorf("%d of %d expected failures do not match exercised cases", len(failures)-expectedFailures, len(failures))
+	}
+	if exercised < minSafeBrowsingCases {
+		t.Errorf("only %d cases exercised, want at least %d", exercised, minSafeBrowsingCases)
+	}
+}
@@ -0,0 +1,420 @@
+package urlutil
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Fingerprint returns the 64-bit FNV-1a hash of u normalized by NormalizeURL.
+// The value is stable across processes and versions as long as the normalization does not change.
+func Fingerprint(u *url.URL) (uint64, error) {
+	u, err := NormalizeURL(u)
+	if err != nil {
+		return 0, err
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(u.String()))
+	return h.Sum64(), nil
+}
+
+// Fingerprint128 is like Fingerprint but returns the 128-bit FNV-1a hash, in big endian.
+func Fingerprint128(u *url.URL) ([16]byte, error) {
+	var fp [16]byte
+
+	u, err := NormalizeURL(u)
+	if err != nil {
+		return fp, err
+	}
+
+	h := fnv.New128a()
+	h.Write([]byte(u.String()))
+	h.Sum(fp[:0])
+	return fp, nil
+}
+
+// SeenSet is a set of URL fingerprints, which can be persisted with WriteTo and reloaded with ReadFrom.
+// Implementations are safe for concurrent use.
+type SeenSet interface {
+	// Add adds fp to the set and reports whether it was not in the set.
+	Add(fp uint64) bool
+	// Contains reports whether fp is in the set.
+	Contains(fp uint64) bool
+
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// AddURL adds the fingerprint of u to s and reports whether it was not in s.
+func AddURL(s SeenSet, u *url.URL) (bool, error) {
+	fp, err := Fingerprint(u)
+	if err != nil {
+		return false, err
+	}
+	return s.Add(fp), nil
+}
+
+var (
+	fingerprintSetMagic = [8]byte{'U', 'R', 'L', 'F', 'P', 'S', 'E', 'T'}
+	bloomFilterMagic    = [8]byte{'U', 'R', 'L', 'B', 'L', 'O', 'O', 'M'}
+)
+
+// ErrInvalidSeenSet is returned when reading a persisted SeenSet of an unknown format.
+var ErrInvalidSeenSet = errors.New("invalid seen set data")
+
+// ReadSeenSet reads a SeenSet written by either FingerprintSet.WriteTo or BloomFilter.WriteTo.
+func ReadSeenSet(r io.Reader) (SeenSet, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(fingerprintSetMagic))
+	if err != nil {
+		return nil, err
+	}
+
+	var s SeenSet
+	switch {
+	case string(magic) == string(fingerprintSetMagic[:]):
+		s = &FingerprintSet{}
+	case string(magic) == string(bloomFilterMagic[:]):
+		s = &BloomFilter{}
+	default:
+		return nil, ErrInvalidSeenSet
+	}
+
+	_, err = s.ReadFrom(br)
+	return s, err
+}
+
+// FingerprintSet is a SeenSet which holds fingerprints exactly. The zero value is an empty set.
+type FingerprintSet struct {
+	mu  sync.RWMutex
+	fps map[uint64]struct{}
+}
+
+func (s *FingerprintSet) Add(fp uint64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.fps[fp]; ok {
+		return false
+	}
+	if s.fps == nil {
+		s.fps = map[uint64]struct{}{}
+	}
+	s.fps[fp] = struct{}{}
+	return true
+}
+
+func (s *FingerprintSet) Contains(fp uint64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.fps[fp]
+	return ok
+}
+
+// Len returns the number of fingerprints in s.
+func (s *FingerprintSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.fps)
+}
+
+// WriteTo writes the fingerprints in s in the sorted order, so that the output is deterministic.
+func (s *FingerprintSet) WriteTo(w io.Writer) (int64, error) {
+	s.mu.RLock()
+	fps := make([]uint64, 0, len(s.fps))
+	for fp := range s.fps {
+		fps = append(fps, fp)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(fps, func(i, j int) bool { return fps[i] < fps[j] })
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	cw.write(fingerprintSetMagic[:])
+	cw.writeUint64(uint64(len(fps)))
+	for _, fp := range fps {
+		cw.writeUint64(fp)
+	}
+	return cw.flush()
+}
+
+// ReadFrom replaces the content of s with the data written by WriteTo.
+func (s *FingerprintSet) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+
+	var magic [8]byte
+	cr.read(magic[:])
+	n := cr.readUint64()
+	if cr.err != nil {
+		return cr.n, cr.err
+	}
+	if magic != fingerprintSetMagic {
+		return cr.n, ErrInvalidSeenSet
+	}
+
+	fps := make(map[uint64]struct{}, minInt(n, 1<<20))
+	for i := uint64(0); i < n; i++ {
+		fps[cr.readUint64()] = struct{}{}
+		if cr.err != nil {
+			return cr.n, cr.err
+		}
+	}
+
+	s.mu.Lock()
+	s.fps = fps
+	s.mu.Unlock()
+
+	return cr.n, nil
+}
+
+const (
+	// defaultBloomFilterN and defaultBloomFilterFPRate are the parameters of zero value BloomFilters.
+	defaultBloomFilterN      = 100000
+	defaultBloomFilterFPRate = 0.01
+
+	// maxBloomFilterHashes is the maximum number of hash functions, which bounds the cost of Add and Contains.
+	maxBloomFilterHashes = 64
+)
+
+// BloomFilter is a SeenSet which may report false positives, using a fixed amount of memory.
+// Use NewBloomFilter to create one. The zero value is an empty filter, which is sized for
+// 100,000 fingerprints with a false positive rate of 1% on the first Add.
+type BloomFilter struct {
+	mu    sync.RWMutex
+	bits  []uint64
+	m     uint64 // number of bits
+	k     uint32 // number of hash functions
+	count uint64
+}
+
+// NewBloomFilter returns a BloomFilter sized so that the false positive rate is about fpRate
+// after n fingerprints are added.
+func NewBloomFilter(n int, fpRate float64) *BloomFilter {
+	f := &BloomFilter{}
+	f.init(n, fpRate)
+	return f
+}
+
+func (f *BloomFilter) init(n int, fpRate float64) {
+	if n < 1 {
+		n = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > maxBloomFilterHashes {
+		k = maxBloomFilterHashes
+	}
+
+	f.bits, f.m, f.k = make([]uint64, (m+63)/64), m, k
+}
+
+// Add adds fp to f and reports whether it was not in f, which may be false for a new fp by a false positive.
+func (f *BloomFilter) Add(fp uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.m == 0 {
+		f.init(defaultBloomFilterN, defaultBloomFilterFPRate)
+	}
+
+	added := false
+	f.each(fp, func(i uint64) {
+		if f.bits[i/64]&(1<<(i%64)) == 0 {
+			f.bits[i/64] |= 1 << (i % 64)
+			added = true
+		}
+	})
+	if added {
+		f.count++
+	}
+	return added
+}
+
+func (f *BloomFilter) Contains(fp uint64) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.m == 0 {
+		return false
+	}
+
+	found := true
+	f.each(fp, func(i uint64) {
+		if f.bits[i/64]&(1<<(i%64)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+// Len returns the approximate number of fingerprints added to f.
+func (f *BloomFilter) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return int(f.count)
+}
+
+// FalsePositiveRate returns the estimated false positive rate of f for the current number of fingerprints.
+func (f *BloomFilter) FalsePositiveRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.m == 0 {
+		return 0
+	}
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.count)/float64(f.m)), float64(f.k))
+}
+
+// each calls fn with the k bit positions of fp, using double hashing.
+// f must be initialized.
+func (f *BloomFilter) each(fp uint64, fn func(uint64)) {
+	h1 := fp
+	h2 := splitmix64(fp) | 1
+	for i := uint32(0); i < f.k; i++ {
+		fn((h1 + uint64(i)*h2) % f.m)
+	}
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	cw.write(bloomFilterMagic[:])
+	cw.writeUint64(f.m)
+	cw.writeUint64(uint64(f.k))
+	cw.writeUint64(f.count)
+	for _, word := range f.bits {
+		cw.writeUint64(word)
+	}
+	return cw.flush()
+}
+
+// ReadFrom replaces the content and the parameters of f with the data written by WriteTo.
+// It returns ErrInvalidSeenSet for parameters out of range, which BloomFilters never have.
+func (f *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countReader{r: r}
+
+	var magic [8]byte
+	cr.read(magic[:])
+	m := cr.readUint64()
+	k := cr.readUint64()
+	count := cr.readUint64()
+	if cr.err != nil {
+		return cr.n, cr.err
+	}
+	if magic != bloomFilterMagic {
+		return cr.n, ErrInvalidSeenSet
+	}
+	// A zero value BloomFilter is written with m = 0 and k = 0
+	if (m == 0) != (k == 0) || k > maxBloomFilterHashes || m > math.MaxUint64-63 {
+		return cr.n, ErrInvalidSeenSet
+	}
+
+	// Allocate as words are actually read, so that a large m in broken data does not exhaust memory
+	words := (m + 63) / 64
+	bits := make([]uint64, 0, minInt(words, 1<<12))
+	for i := uint64(0); i < words; i++ {
+		bits = append(bits, cr.readUint64())
+		if cr.err != nil {
+			return cr.n, cr.err
+		}
+	}
+
+	f.mu.Lock()
+	f.bits, f.m, f.k, f.count = bits, m, uint32(k), count
+	f.mu.Unlock()
+
+	return cr.n, nil
+}
+
+func minInt(a, b uint64) int {
+	if a < b {
+		return int(a)
+	}
+	return int(b)
+}
+
+// countWriter writes to a buffered writer counting bytes, keeping the first error.
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) write(p []byte) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func (cw *countWriter) writeUint64(v uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	cw.write(b[:])
+}
+
+func (cw *countWriter) flush() (int64, error) {
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// countReader reads from a reader counting bytes, keeping the first error.
+type countReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (cr *countReader) read(p []byte) {
+	if cr.err != nil {
+		return
+	}
+	n, err := io.ReadFull(cr.r, p)
+	cr.n += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		cr.err = fmt.Errorf("reading seen set: %w", err)
+	}
+}
+
+func (cr *countReader) readUint64() uint64 {
+	var b [8]byte
+	cr.read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
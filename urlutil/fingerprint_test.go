@@ -0,0 +1,230 @@
+package urlutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	mustParse := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+
+	a, err := Fingerprint(mustParse("HTTP://Example.COM:80/a/./b/../c?%7e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Fingerprint(mustParse("http://example.com/a/c?~"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Fingerprint(mustParse("http://example.com/a/d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a != b {
+		t.Errorf("fingerprints of equivalent URLs differ: %x != %x", a, b)
+	}
+	if a == c {
+		t.Errorf("fingerprints of different URLs are the same: %x", a)
+	}
+
+	// FNV-1a of "http://example.com/a/c?~"
+	if want := uint64(0x88d39567a7374683); b != want {
+		t.Errorf("Fingerprint = %#x, want %#x", b, want)
+	}
+
+	a128, err := Fingerprint128(mustParse("HTTP://Example.COM:80/a/./b/../c?%7e"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b128, err := Fingerprint128(mustParse("http://example.com/a/c?~"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a128 != b128 {
+		t.Errorf("128-bit fingerprints of equivalent URLs differ: %x != %x", a128, b128)
+	}
+
+	if _, err := Fingerprint(mustParse("http://example.com:99999/")); err == nil {
+		t.Error("Fingerprint should fail for invalid URLs")
+	}
+}
+
+func TestSeenSet(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func() SeenSet
+	}{
+		{"FingerprintSet", func() SeenSet { return &FingerprintSet{} }},
+		{"BloomFilter", func() SeenSet { return NewBloomFilter(1000, 0.001) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := test.new()
+
+			for i := 0; i < 100; i++ {
+				u, _ := url.Parse(fmt.Sprintf("http://example.com/page/%d", i))
+				added, err := AddURL(s, u)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !added {
+					t.Errorf("%s: should be added", u)
+				}
+			}
+
+			u, _ := url.Parse("HTTP://EXAMPLE.com/page/%31")
+			if added, _ := AddURL(s, u); added {
+				t.Errorf("%s: should have been seen", u)
+			}
+
+			path := filepath.Join(t.TempDir(), "seen")
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.WriteTo(f); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			f, err = os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			loaded, err := ReadSeenSet(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprintf("%T", loaded) != fmt.Sprintf("%T", s) {
+				t.Errorf("loaded %T, want %T", loaded, s)
+			}
+
+			for i := 0; i < 100; i++ {
+				fp, _ := Fingerprint(&url.URL{Scheme: "http", Host: "example.com", Path: fmt.Sprintf("/page/%d", i)})
+				if !loaded.Contains(fp) {
+					t.Errorf("/page/%d should be in the loaded set", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBloomFilter_FalsePositiveRate(t *testing.T) {
+	const n = 10000
+	f := NewBloomFilter(n, 0.01)
+
+	for i := uint64(0); i < n; i++ {
+		f.Add(splitmix64(i))
+	}
+
+	falsePositives := 0
+	for i := uint64(n); i < 2*n; i++ {
+		if f.Contains(splitmix64(i)) {
+			falsePositives++
+		}
+	}
+
+	if rate := float64(falsePositives) / n; rate > 0.02 {
+		t.Errorf("false positive rate %v is too high", rate)
+	}
+	if rate := f.FalsePositiveRate(); rate < 0.005 || rate > 0.02 {
+		t.Errorf("estimated false positive rate %v is off", rate)
+	}
+}
+
+func TestReadSeenSet_Invalid(t *testing.T) {
+	if _, err := ReadSeenSet(bytes.NewReader([]byte("NOTASEENSET"))); !errors.Is(err, ErrInvalidSeenSet) {
+		t.Errorf("got %v, want ErrInvalidSeenSet", err)
+	}
+
+	var buf bytes.Buffer
+	s := &FingerprintSet{}
+	s.Add(1)
+	s.Add(2)
+	s.WriteTo(&buf)
+
+	if _, err := ReadSeenSet(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Error("reading truncated data should fail")
+	}
+}
+
+func TestBloomFilter_ZeroValue(t *testing.T) {
+	var f BloomFilter
+	if f.Contains(1) {
+		t.Error("zero value should be empty")
+	}
+	if !f.Add(1) {
+		t.Error("Add(1) should report a new fingerprint")
+	}
+	if !f.Contains(1) || f.Len() != 1 {
+		t.Errorf("Contains(1) = %v, Len() = %d after Add(1)", f.Contains(1), f.Len())
+	}
+
+	var buf bytes.Buffer
+	if _, err := (&BloomFilter{}).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := ReadSeenSet(&buf)
+	if err != nil {
+		t.Fatalf("reading a zero value: %v", err)
+	}
+	if !s.Add(1) || !s.Contains(1) {
+		t.Error("loaded zero value should be usable")
+	}
+}
+
+func TestBloomFilter_ReadFrom_Invalid(t *testing.T) {
+	header := func(m, k uint64) []byte {
+		b := append([]byte{}, bloomFilterMagic[:]...)
+		for _, v := range []uint64{m, k, 0} {
+			var w [8]byte
+			binary.LittleEndian.PutUint64(w[:], v)
+			b = append(b, w[:]...)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		invalid bool
+	}{
+		{name: "too many hashes", data: header(64, 1000), invalid: true},
+		{name: "no hashes", data: header(64, 0), invalid: true},
+		{name: "no bits", data: header(0, 3), invalid: true},
+		{name: "overflowing bits", data: header(math.MaxUint64, 3), invalid: true},
+		{name: "missing bits", data: header(1<<62, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f BloomFilter
+			_, err := f.ReadFrom(bytes.NewReader(tt.data))
+			if err == nil {
+				t.Fatal("ReadFrom should fail")
+			}
+			if tt.invalid && !errors.Is(err, ErrInvalidSeenSet) {
+				t.Errorf("got %v, want ErrInvalidSeenSet", err)
+			}
+			if f.Add(1); !f.Contains(1) {
+				t.Error("BloomFilter should be usable after failed ReadFrom")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+//go:build ignore
+// +build ignore
+
+// gen_publicsuffix.go generates publicsuffix.txt, the snapshot of the Public Suffix List
+// embedded in this package, from a local copy of public_suffix_list.dat.
+//
+// Usage:
+//
+//	curl -O https://publicsuffix.org/list/public_suffix_list.dat
+//	go run gen_publicsuffix.go -input public_suffix_list.dat
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+func main() {
+	input := flag.String("input", "public_suffix_list.dat", "path to public_suffix_list.dat")
+	output := flag.String("output", "publicsuffix.txt", "path to output")
+	flag.Parse()
+
+	in, err := os.Open(*input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := generate(out, in); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(w io.Writer, r io.Reader) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# Code generated by gen_publicsuffix.go from public_suffix_list.dat; DO NOT EDIT.")
+	fmt.Fprintln(bw, "# The Public Suffix List is subject to the terms of the Mozilla Public License, v. 2.0.")
+	fmt.Fprintln(bw, "# https://publicsuffix.org/")
+	fmt.Fprintln(bw, "# Each line is a rule in ASCII, followed by \"private\" for rules in PRIVATE DOMAINS section.")
+
+	private := false
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case strings.Contains(line, "===BEGIN PRIVATE DOMAINS==="):
+			private = true
+			continue
+		case strings.Contains(line, "===END PRIVATE DOMAINS==="):
+			private = false
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+
+		// Each line is only read up to the first whitespace
+		rule := strings.Fields(line)[0]
+
+		prefix := ""
+		if strings.HasPrefix(rule, "!") {
+			prefix, rule = "!", rule[1:]
+		}
+
+		labels := strings.Split(rule, ".")
+		for i, label := range labels {
+			if label == "*" {
+				continue
+			}
+			ascii, err := idna.ToASCII(label)
+			if err != nil {
+				return fmt.Errorf("rule %q: %w", line, err)
+			}
+			labels[i] = strings.ToLower(ascii)
+		}
+
+		rule = prefix + strings.Join(labels, ".")
+		if private {
+			rule += " private"
+		}
+		fmt.Fprintln(bw, rule)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
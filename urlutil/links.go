@@ -0,0 +1,290 @@
+package urlutil
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Link is a link found in an HTML document.
+type Link struct {
+	// URL is the absolute URL of the link, normalized by NormalizeURL.
+	URL *url.URL
+	// Tag is the name of the element, like "a".
+	Tag string
+	// Attr is the name of the attribute the link is found in, like "href".
+	// It is "style" for links in style attributes and <style> elements.
+	Attr string
+	// Rel is the list of link types in the rel attribute in lower case, like "nofollow" and "canonical".
+	Rel []string
+}
+
+// HasRel reports whether l has the link type rel.
+func (l *Link) HasRel(rel string) bool {
+	for _, r := range l.Rel {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkExtractor extracts links from an HTML document as it is read.
+//
+// Links are collected from href of <a>, <area> and <link>, src of <img>, <script>,
+// <iframe>, <frame>, <source>, <video> and <audio>, srcset of <img> and <source>,
+// <meta http-equiv="refresh">, and url() and @import in style attributes and <style> elements.
+// Links are resolved against the base URL, which is replaced by the first <base href> in the
+// document, and normalized by NormalizeURL. Links which cannot be resolved or normalized,
+// and those with javascript: or data: schemes are skipped.
+type LinkExtractor struct {
+	z       *html.Tokenizer
+	base    *url.URL
+	hasBase bool
+	inStyle bool
+	queue   []*Link
+}
+
+// NewLinkExtractor returns a LinkExtractor which reads an HTML document at base from r.
+func NewLinkExtractor(r io.Reader, base *url.URL) *LinkExtractor {
+	return &LinkExtractor{
+		z:    html.NewTokenizer(r),
+		base: base,
+	}
+}
+
+// ExtractLinks extracts all the links in an HTML document at base from r.
+func ExtractLinks(r io.Reader, base *url.URL) ([]*Link, error) {
+	e := NewLinkExtractor(r, base)
+
+	var links []*Link
+	for {
+		link, err := e.Next()
+		if err == io.EOF {
+			return links, nil
+		}
+		if err != nil {
+			return links, err
+		}
+		links = append(links, link)
+	}
+}
+
+// Next returns the next link in the document. It returns io.EOF at the end of the document.
+func (e *LinkExtractor) Next() (*Link, error) {
+	for len(e.queue) == 0 {
+		tt := e.z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return nil, e.z.Err()
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			e.startTag(e.z.Token())
+
+		case html.EndTagToken:
+			if name, _ := e.z.TagName(); atom.Lookup(name) == atom.Style {
+				e.inStyle = false
+			}
+
+		case html.TextToken:
+			if e.inStyle {
+				e.addStyle("style", "style", string(e.z.Text()))
+			}
+		}
+	}
+
+	link := e.queue[0]
+	e.queue = e.queue[1:]
+	return link, nil
+}
+
+func (e *LinkExtractor) startTag(t html.Token) {
+	attrs := map[string]string{}
+	for _, a := range t.Attr {
+		if a.Namespace != "" {
+			continue
+		}
+		if _, ok := attrs[a.Key]; !ok {
+			attrs[a.Key] = a.Val
+		}
+	}
+
+	var rel []string
+	if v, ok := attrs["rel"]; ok {
+		rel = strings.Fields(strings.ToLower(v))
+	}
+
+	tag := t.Data
+	switch t.DataAtom {
+	case atom.Base:
+		if href, ok := attrs["href"]; ok && !e.hasBase {
+			e.hasBase = true
+			if u := e.resolve(href); u != nil {
+				e.base = u
+			}
+		}
+
+	case atom.A, atom.Area, atom.Link:
+		e.add(tag, "href", attrs, rel)
+
+	case atom.Img, atom.Source:
+		e.add(tag, "src", attrs, rel)
+		if srcset, ok := attrs["srcset"]; ok {
+			for _, s := range parseSrcset(srcset) {
+				e.addURL(tag, "srcset", s, rel)
+			}
+		}
+
+	case atom.Script, atom.Iframe, atom.Frame, atom.Video, atom.Audio:
+		e.add(tag, "src", attrs, rel)
+
+	case atom.Meta:
+		if strings.EqualFold(attrs["http-equiv"], "refresh") {
+			if s, ok := parseMetaRefresh(attrs["content"]); ok {
+				e.addURL(tag, "content", s, rel)
+			}
+		}
+
+	case atom.Style:
+		e.inStyle = t.Type == html.StartTagToken
+	}
+
+	if style, ok := attrs["style"]; ok {
+		e.addStyle(tag, "style", style)
+	}
+}
+
+func (e *LinkExtractor) add(tag, attr string, attrs map[string]string, rel []string) {
+	if s, ok := attrs[attr]; ok {
+		e.addURL(tag, attr, s, rel)
+	}
+}
+
+func (e *LinkExtractor) addStyle(tag, attr, css string) {
+	for _, s := range parseCSSURLs(css) {
+		e.addURL(tag, attr, s, nil)
+	}
+}
+
+func (e *LinkExtractor) addURL(tag, attr, s string, rel []string) {
+	if strings.TrimSpace(s) == "" {
+		return
+	}
+
+	u := e.resolve(s)
+	if u == nil || u.Scheme == "javascript" || u.Scheme == "data" {
+		return
+	}
+
+	u, err := NormalizeURL(u)
+	if err != nil {
+		return
+	}
+
+	e.queue = append(e.queue, &Link{URL: u, Tag: tag, Attr: attr, Rel: rel})
+}
+
+func (e *LinkExtractor) resolve(s string) *url.URL {
+	ref, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return nil
+	}
+
+	if e.base == nil {
+		if ref.Scheme == "" {
+			return nil
+		}
+		return ref
+	}
+
+	u, err := Resolve(e.base, ref)
+	if err != nil {
+		return nil
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	return u
+}
+
+// parseSrcset returns the URLs of image candidates in a srcset attribute.
+// https://html.spec.whatwg.org/multipage/images.html#parsing-a-srcset-attribute
+func parseSrcset(srcset string) []string {
+	var urls []string
+	s := srcset
+	for {
+		s = strings.TrimLeft(s, " \t\n\f\r,")
+		if s == "" {
+			return urls
+		}
+
+		i := strings.IndexAny(s, " \t\n\f\r")
+		if i < 0 {
+			i = len(s)
+		}
+		u := s[:i]
+		s = s[i:]
+
+		if strings.HasSuffix(u, ",") {
+			u = strings.TrimRight(u, ",")
+		} else {
+			// Skip descriptors
+			if j := strings.IndexByte(s, ','); j >= 0 {
+				s = s[j+1:]
+			} else {
+				s = ""
+			}
+		}
+
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+}
+
+// parseMetaRefresh returns the URL in the content attribute of <meta http-equiv="refresh">.
+// https://html.spec.whatwg.org/multipage/semantics.html#attr-meta-http-equiv-refresh
+func parseMetaRefresh(content string) (string, bool) {
+	s := strings.TrimLeft(content, " \t\n\f\r")
+	s = strings.TrimLeft(s, "0123456789.")
+	s = strings.TrimLeft(s, " \t\n\f\r")
+	if s == "" || (s[0] != ';' && s[0] != ',') {
+		return "", false
+	}
+	s = strings.TrimLeft(s[1:], " \t\n\f\r")
+
+	if len(s) >= 3 && strings.EqualFold(s[:3], "url") {
+		rest := strings.TrimLeft(s[3:], " \t\n\f\r")
+		if strings.HasPrefix(rest, "=") {
+			s = strings.TrimLeft(rest[1:], " \t\n\f\r")
+		}
+	}
+
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		quote := s[0]
+		s = s[1:]
+		if i := strings.IndexByte(s, quote); i >= 0 {
+			s = s[:i]
+		}
+	}
+
+	return s, s != ""
+}
+
+var rxCSSURL = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^"'()\s]*))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// parseCSSURLs returns the URLs in url() and @import of CSS.
+func parseCSSURLs(css string) []string {
+	var urls []string
+	for _, m := range rxCSSURL.FindAllStringSubmatch(css, -1) {
+		for _, s := range m[1:] {
+			if s != "" {
+				urls = append(urls, s)
+				break
+			}
+		}
+	}
+	return urls
+}
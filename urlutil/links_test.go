@@ -0,0 +1,96 @@
+package urlutil
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	f, err := os.Open("testdata/links.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	links, err := ExtractLinks(f, mustParseURL(t, "http://example.com/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]string, len(links))
+	for i, l := range links {
+		got[i] = strings.TrimSpace(l.Tag + " " + l.Attr + " " + l.URL.String() + " " + strings.Join(l.Rel, ","))
+	}
+
+	want := []string{
+		"meta content http://example.com/refreshed?a=1",
+		"link href https://example.com/docs/page canonical",
+		"link href http://example.com/docs/style.css stylesheet,preload",
+		"style style http://example.com/docs/print.css",
+		"style style http://example.com/docs/img/bg.png",
+		"script src http://cdn.example.net/app.js",
+		"a href http://example.com/about",
+		"a href https://other.example/ nofollow,external",
+		"a href http://example.com/docs/#section",
+		"div style http://example.com/img/div.png",
+		"img src http://example.com/docs/a.png",
+		"img srcset http://example.com/docs/a-1x.png",
+		"img srcset http://example.com/docs/a-2x.png",
+		"img srcset http://example.com/docs/a,b.png",
+		"source srcset http://example.com/docs/pic.webp",
+		"iframe src http://example.com/embed/1",
+		"a href http://example.com/docs/svg-link",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, l := range links {
+		if l.Tag == "a" && l.HasRel("nofollow") != strings.Contains(l.URL.Host, "other") {
+			t.Errorf("%s: HasRel(nofollow) = %v", l.URL, l.HasRel("nofollow"))
+		}
+	}
+}
+
+func TestParseSrcset(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"a.png", []string{"a.png"}},
+		{"a.png 1x, b.png 2x", []string{"a.png", "b.png"}},
+		{" a.png 100w ,b.png,c.png 1.5x ", []string{"a.png", "b.png,c.png"}},
+		{"data:image/png;base64,xyz 1x", []string{"data:image/png;base64,xyz"}},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		if got := parseSrcset(test.in); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseSrcset(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseMetaRefresh(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"0; url=http://example.com/", "http://example.com/"},
+		{"5;URL='/next'", "/next"},
+		{"3, /next", "/next"},
+		{"1.5 ; Url = \"/quoted\" extra", "/quoted"},
+		{"10", ""},
+		{"x; url=/bad", ""},
+	}
+
+	for _, test := range tests {
+		got, ok := parseMetaRefresh(test.in)
+		if ok != (test.want != "") || got != test.want {
+			t.Errorf("parseMetaRefresh(%q) = (%q, %v), want %q", test.in, got, ok, test.want)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package urlutil
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
 	"net/url"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/net/idna"
 )
@@ -12,41 +18,56 @@ func CloneURL(u *url.URL) *url.URL {
 	return &u2
 }
 
-var defaultPorts = map[string]string{
-	"https": "443",
-	"http":  "80",
-}
+// hostProfile maps hostnames like WHATWG URL Standard does.
+// https://url.spec.whatwg.org/#concept-domain-to-ascii
+var hostProfile = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+	idna.StrictDomainName(false),
+	idna.CheckHyphens(false),
+)
 
 // NormalizeURL normalizes URL u in such manner:
 // - all components should be represented in ASCII
 // - precent encoding in upper case
+// URLs of schemes with normalizers registered by RegisterSchemeNormalizer are normalized by them instead,
+// and the other opaque URLs, like "tel:+1-201-555-0123", are only lowercased their schemes.
 // https://datatracker.ietf.org/doc/html/rfc3986#section-6
 func NormalizeURL(u *url.URL) (*url.URL, error) {
 	u = CloneURL(u)
 
 	u.Scheme = strings.ToLower(u.Scheme)
 
-	port := u.Port()
-	if port == defaultPorts[u.Scheme] {
-		port = ""
+	if normalize := schemeNormalizer(u.Scheme); normalize != nil {
+		return normalize(u)
+	}
+	if u.Opaque != "" {
+		return u, nil
 	}
 
-	hostname, err := idna.ToASCII(u.Hostname())
+	port, err := normalizePort(u.Port(), u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := normalizeHost(u.Hostname())
 	if err != nil {
 		return nil, err
 	}
-	hostname = strings.ToLower(hostname)
 
 	u.Host = hostname
 	if port != "" {
 		u.Host += ":" + port
 	}
 
+	u.User = normalizeUserinfo(u.User)
+
 	path := u.RawPath
 	if path == "" {
 		path = u.Path
 	}
-	if path == "" {
+	if path == "" && u.Host != "" {
 		path = "/"
 	}
 
@@ -57,6 +78,12 @@ func NormalizeURL(u *url.URL) (*url.URL, error) {
 
 	path = removeDotSegments(path)
 
+	// Without an authority, a path cannot begin with "//"
+	// https://datatracker.ietf.org/doc/html/rfc3986#section-3.3
+	if u.Host == "" && u.User == nil && strings.HasPrefix(path, "//") {
+		path = "/." + path
+	}
+
 	u.Path, err = url.PathUnescape(path)
 	if err != nil {
 		return nil, err
@@ -72,8 +99,213 @@ func NormalizeURL(u *url.URL) (*url.URL, error) {
 	return u, nil
 }
 
+func normalizeHost(hostname string) (string, error) {
+	if strings.Contains(hostname, ":") {
+		// IPv6 address, possibly with a zone
+		addr, zone := hostname, ""
+		if i := strings.IndexByte(addr, '%'); i >= 0 {
+			addr, zone = addr[:i], addr[i:]
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return "", fmt.Errorf("invalid IPv6 address %q", hostname)
+		}
+		return "[" + formatIPv6(ip) + strings.ToLower(zone) + "]", nil
+	}
+
+	if !utf8.ValidString(hostname) {
+		return "", fmt.Errorf("invalid hostname %q", hostname)
+	}
+
+	hostname, err := hostProfile.ToASCII(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	hostname = strings.ToLower(hostname)
+
+	// Mapping may produce characters not allowed in reg-name
+	// https://datatracker.ietf.org/doc/html/rfc3986#section-3.2.2
+	if i := strings.IndexFunc(hostname, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || strings.ContainsRune("-._~!$&'()*+,;=", r))
+	}); i >= 0 {
+		return "", fmt.Errorf("invalid hostname %q", hostname)
+	}
+
+	if endsInNumber(hostname) {
+		ip, err := parseIPv4Host(hostname)
+		if err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	}
+
+	return hostname, nil
+}
+
+// endsInNumber reports whether the last label of hostname is a number,
+// in which case hostname must be an IPv4 address.
+// https://url.spec.whatwg.org/#ends-in-a-number-checker
+func endsInNumber(hostname string) bool {
+	parts := strings.Split(hostname, ".")
+	if parts[len(parts)-1] == "" {
+		if len(parts) == 1 {
+			return false
+		}
+		parts = parts[:len(parts)-1]
+	}
+
+	last := parts[len(parts)-1]
+	if last != "" && strings.Trim(last, "0123456789") == "" {
+		return true
+	}
+
+	_, err := parseIPv4Number(last)
+	return err == nil
+}
+
+// parseIPv4Host parses IPv4 addresses in the forms accepted by WHATWG URL Standard,
+// including hexadecimal, octal and less than four parts, like "0x7f.1".
+// https://url.spec.whatwg.org/#concept-ipv4-parser
+func parseIPv4Host(hostname string) (net.IP, error) {
+	parts := strings.Split(hostname, ".")
+	if parts[len(parts)-1] == "" && len(parts) > 1 {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > 4 {
+		return nil, fmt.Errorf("invalid IPv4 address %q", hostname)
+	}
+
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		n, err := parseIPv4Number(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPv4 address %q", hostname)
+		}
+		if i < len(parts)-1 && n > 255 {
+			return nil, fmt.Errorf("invalid IPv4 address %q", hostname)
+		}
+		numbers[i] = n
+	}
+
+	// The last number fills the remaining bytes
+	last := numbers[len(numbers)-1]
+	if last >= 1<<(8*(5-len(numbers))) {
+		return nil, fmt.Errorf("invalid IPv4 address %q", hostname)
+	}
+
+	ipv4 := last
+	for i, n := range numbers[:len(numbers)-1] {
+		ipv4 += n << (8 * (3 - i))
+	}
+
+	return net.IPv4(byte(ipv4>>24), byte(ipv4>>16), byte(ipv4>>8), byte(ipv4)), nil
+}
+
+// parseIPv4Number parses a part of IPv4 addresses, which is decimal, hexadecimal with "0x" prefix,
+// or octal with "0" prefix.
+func parseIPv4Number(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty IPv4 number")
+	}
+
+	base := 10
+	if len(s) >= 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		base = 16
+		s = s[2:]
+	} else if len(s) >= 2 && s[0] == '0' {
+		base = 8
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseUint(s, base, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return math.MaxUint64, nil
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// formatIPv6 formats ip in the canonical form of RFC 5952, but never in the dotted-decimal notation
+// unlike net.IP.String, as WHATWG URL Standard does.
+// https://url.spec.whatwg.org/#concept-ipv6-serializer
+func formatIPv6(ip net.IP) string {
+	ip = ip.To16()
+
+	// Find the first longest run of zero pieces
+	start, length := -1, 1
+	for i := 0; i < 8; {
+		j := i
+		for j < 8 && ip[2*j] == 0 && ip[2*j+1] == 0 {
+			j++
+		}
+		if j-i > length {
+			start, length = i, j-i
+		}
+		i = j + 1
+	}
+
+	var b strings.Builder
+	for i := 0; i < 8; i++ {
+		if i == start {
+			b.WriteString("::")
+			i += length - 1
+			continue
+		}
+		if i > 0 && i != start+length {
+			b.WriteByte(':')
+		}
+		b.WriteString(strconv.FormatUint(uint64(ip[2*i])<<8|uint64(ip[2*i+1]), 16))
+	}
+	return b.String()
+}
+
+func normalizePort(port, scheme string) (string, error) {
+	if port == "" {
+		return "", nil
+	}
+
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+
+	port = strconv.FormatUint(n, 10)
+	if defaultPort, ok := DefaultPort(scheme); ok && port == defaultPort {
+		return "", nil
+	}
+
+	return port, nil
+}
+
+// normalizeUserinfo removes empty password and empty userinfo.
+func normalizeUserinfo(user *url.Userinfo) *url.Userinfo {
+	if user == nil {
+		return nil
+	}
+
+	if password, ok := user.Password(); ok && password != "" {
+		return user
+	}
+
+	if user.Username() == "" {
+		return nil
+	}
+
+	return url.User(user.Username())
+}
+
 // https://datatracker.ietf.org/doc/html/rfc3986#section-5.2.4
 func removeDotSegments(in string) string {
+	if in == "" {
+		return in
+	}
+
 	segs := strings.Split(in, "/")
 	result := make([]string, 0, len(segs))
 
@@ -145,7 +377,7 @@ func normalizeComponent(component string, special string, mode escapeMode) (stri
 			part, sep, component = component, "", ""
 		}
 
-		unescaped, err := e.unescape(part)
+		unescaped, err := e.unescape(escapeInvalidPercents(part))
 		if err != nil {
 			return "", err
 		}
@@ -155,3 +387,24 @@ func normalizeComponent(component string, special string, mode escapeMode) (stri
 
 	return escaped, nil
 }
+
+// escapeInvalidPercents escapes "%" which does not start a percent-encoding.
+func escapeInvalidPercents(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && (i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2])) {
+			b.WriteString("%25")
+		} else {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
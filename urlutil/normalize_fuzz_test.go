@@ -0,0 +1,48 @@
+//go:build go1.18
+// +build go1.18
+
+package urlutil
+
+import (
+	"net/url"
+	"testing"
+)
+
+func FuzzNormalizeURL(f *testing.F) {
+	for _, s := range []string{
+		"http://www.example.com:80/",
+		"https://はじめよう.みんな/はじめよう.みんな",
+		"https://localhost/%7e%41%5E/🤗?q=%7E%41%5E🤗/",
+		"https://localhost/a/./b/../c//d/",
+		"http://user:@[::1]:/%2F?a=b;c#d",
+		"HTTP://%GH@EXAMPLE.COM/%",
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return
+		}
+
+		n1, err := NormalizeURL(u)
+		if err != nil {
+			return
+		}
+
+		u2, err := url.Parse(n1.String())
+		if err != nil {
+			t.Fatalf("NormalizeURL(%q) = %q, which cannot be parsed: %v", s, n1, err)
+		}
+
+		n2, err := NormalizeURL(u2)
+		if err != nil {
+			t.Fatalf("NormalizeURL(%q) = %q, which cannot be normalized: %v", s, n1, err)
+		}
+
+		if n1.String() != n2.String() {
+			t.Errorf("NormalizeURL is not idempotent: %q -> %q -> %q", s, n1, n2)
+		}
+	})
+}
@@ -67,6 +67,106 @@ func TestNormalizeURL(t *testing.T) {
 			in:   "HTTPS://WWW.EXAMPLE.COM/",
 			want: "https://www.example.com/",
 		},
+		{
+			name: "IPv6 host",
+			in:   "http://[2001:DB8::1]:80/",
+			want: "http://[2001:db8::1]/",
+		},
+		{
+			name: "IPv6 host with port",
+			in:   "http://[::1]:8080/",
+			want: "http://[::1]:8080/",
+		},
+		{
+			name: "Compress IPv6 host",
+			in:   "http://[0:0::1]/",
+			want: "http://[::1]/",
+		},
+		{
+			name: "IPv4-mapped IPv6 host",
+			in:   "http://[::FFFF:127.0.0.1]/",
+			want: "http://[::ffff:7f00:1]/",
+		},
+		{
+			name: "IPv6 host with the longest zero run",
+			in:   "http://[2001:0:0:1:0:0:0:1]/",
+			want: "http://[2001:0:0:1::1]/",
+		},
+		{
+			name: "IPv4 host in hexadecimal",
+			in:   "http://0x7f.1/",
+			want: "http://127.0.0.1/",
+		},
+		{
+			name: "IPv4 host in a number",
+			in:   "http://2130706433/",
+			want: "http://127.0.0.1/",
+		},
+		{
+			name: "IPv4 host in octal",
+			in:   "http://0177.000.000.001/",
+			want: "http://127.0.0.1/",
+		},
+		{
+			name: "IPv4 host with leading zeros",
+			in:   "http://127.000.000.001/",
+			want: "http://127.0.0.1/",
+		},
+		{
+			name: "IPv4 host with a trailing dot",
+			in:   "http://127.0.0.1./",
+			want: "http://127.0.0.1/",
+		},
+		{
+			name:    "IPv4 host out of range",
+			in:      "http://127.0.0.256/",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid IPv4 host",
+			in:      "http://example.09/",
+			wantErr: true,
+		},
+		{
+			name: "Hostname ending in a non-number",
+			in:   "http://1.2.3.example/",
+			want: "http://1.2.3.example/",
+		},
+		{
+			name: "Remove empty userinfo",
+			in:   "http://:@example.com/",
+			want: "http://example.com/",
+		},
+		{
+			name: "Remove empty password",
+			in:   "http://user:@example.com/",
+			want: "http://user@example.com/",
+		},
+		{
+			name: "Keep password",
+			in:   "http://:pass@example.com/",
+			want: "http://:pass@example.com/",
+		},
+		{
+			name: "Remove leading zeros of port",
+			in:   "http://example.com:00080/",
+			want: "http://example.com/",
+		},
+		{
+			name:    "Port out of range",
+			in:      "http://example.com:65536/",
+			wantErr: true,
+		},
+		{
+			name: "Escape invalid percent-encodings",
+			in:   "http://example.com/?q=%G&r=%",
+			want: "http://example.com/?q=%25G&r=%25",
+		},
+		{
+			name: "Map hostname",
+			in:   "http://Ｇｏ.com/",
+			want: "http://go.com/",
+		},
 		{
 			name: "Clean path",
 			in:   "https://localhost/a/./b/../c//d/",
@@ -85,6 +185,9 @@ func TestNormalizeURL(t *testing.T) {
 				t.Errorf("NormalizeURL() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			if got.String() != tt.want {
 				t.Errorf("NormalizeURL() = %v, want %v", got, tt.want)
 			}
@@ -0,0 +1,243 @@
+package urlutil
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// NormalizeOptions configures normalizations applied by NormalizeOptions.Normalize in addition to
+// those of NormalizeURL. The zero value applies no additional normalizations.
+type NormalizeOptions struct {
+	// SortQuery sorts query parameters by their names, keeping the order of parameters of the same name.
+	// Separators, "&" or ";", are kept in their positions.
+	SortQuery bool
+	// RemoveEmptyQuery removes "?" with an empty query.
+	RemoveEmptyQuery bool
+	// RemoveFragment removes fragments. Empty fragments are always removed by net/url.
+	RemoveFragment bool
+	// RemoveTrackingParams removes query parameters which match TrackingParams.
+	RemoveTrackingParams bool
+	// RemoveWWW removes "www." prefixes from hostnames.
+	RemoveWWW bool
+	// RemoveDirectoryIndex removes the last path segment which is one of DirectoryIndexNames.
+	RemoveDirectoryIndex bool
+	// CollapseSlashes replaces consecutive slashes in paths with single ones.
+	CollapseSlashes bool
+	// PercentEncodingCase specifies the case of hexadecimal digits in percent-encodings.
+	PercentEncodingCase PercentEncodingCase
+	// TrailingSlash specifies how to treat trailing slashes of non-root paths.
+	TrailingSlash TrailingSlashPolicy
+}
+
+// PercentEncodingCase is the case of hexadecimal digits in percent-encodings.
+type PercentEncodingCase int
+
+const (
+	// PercentEncodingUpper uses uppercase digits as RFC 3986 recommends.
+	PercentEncodingUpper PercentEncodingCase = iota
+	PercentEncodingLower
+)
+
+// TrailingSlashPolicy specifies how to treat trailing slashes of paths.
+type TrailingSlashPolicy int
+
+const (
+	TrailingSlashKeep TrailingSlashPolicy = iota
+	TrailingSlashAdd
+	TrailingSlashRemove
+)
+
+var (
+	// SafeNormalization only applies normalizations which preserve semantics of URLs.
+	SafeNormalization = NormalizeOptions{
+		RemoveEmptyQuery: true,
+	}
+
+	// AggressiveNormalization applies normalizations which may change semantics of URLs,
+	// which are useful for deduplicating URLs.
+	AggressiveNormalization = NormalizeOptions{
+		SortQuery:            true,
+		RemoveEmptyQuery:     true,
+		RemoveFragment:       true,
+		RemoveTrackingParams: true,
+		RemoveWWW:            true,
+		RemoveDirectoryIndex: true,
+		CollapseSlashes:      true,
+		TrailingSlash:        TrailingSlashRemove,
+	}
+)
+
+// TrackingParams is a list of names of query parameters removed by NormalizeOptions.RemoveTrackingParams.
+// A name ending with "*" matches names with the prefix.
+var TrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"mc_cid",
+	"mc_eid",
+	"yclid",
+	"_ga",
+	"_hsenc",
+	"_hsmi",
+}
+
+// DirectoryIndexNames is a list of file names removed by NormalizeOptions.RemoveDirectoryIndex.
+var DirectoryIndexNames = []string{
+	"index.html",
+	"index.htm",
+	"index.shtml",
+	"index.php",
+	"index.asp",
+	"index.aspx",
+	"index.jsp",
+	"default.asp",
+	"default.aspx",
+}
+
+var rxPercentEncoding = regexp.MustCompile(`%[0-9A-Fa-f]{2}`)
+
+// Normalize normalizes u like NormalizeURL does and then applies normalizations specified by o.
+func (o NormalizeOptions) Normalize(u *url.URL) (*url.URL, error) {
+	u, err := NormalizeURL(u)
+	if err != nil {
+		return nil, err
+	}
+
+	for o.RemoveWWW && strings.HasPrefix(u.Host, "www.") && strings.Contains(u.Hostname()[4:], ".") {
+		u.Host = u.Host[4:]
+	}
+
+	path := u.EscapedPath()
+	if o.CollapseSlashes {
+		path = collapseSlashes(path)
+	}
+	if o.RemoveDirectoryIndex {
+		path = removeDirectoryIndex(path)
+	}
+	switch o.TrailingSlash {
+	case TrailingSlashAdd:
+		if !strings.HasSuffix(path, "/") {
+			path += "/"
+		}
+	case TrailingSlashRemove:
+		path = removeTrailingSlash(path)
+		// Removing a trailing slash may expose a directory index, like "/a/index.html/"
+		for o.RemoveDirectoryIndex {
+			p := removeTrailingSlash(removeDirectoryIndex(path))
+			if p == path {
+				break
+			}
+			path = p
+		}
+	}
+
+	if o.RemoveTrackingParams || o.SortQuery {
+		u.RawQuery = o.filterQuery(u.RawQuery)
+	}
+	if o.RemoveEmptyQuery && u.RawQuery == "" {
+		u.ForceQuery = false
+	}
+
+	if o.RemoveFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	if o.PercentEncodingCase == PercentEncodingLower {
+		path = rxPercentEncoding.ReplaceAllStringFunc(path, strings.ToLower)
+		u.RawQuery = rxPercentEncoding.ReplaceAllStringFunc(u.RawQuery, strings.ToLower)
+		if u.Fragment != "" {
+			u.RawFragment = rxPercentEncoding.ReplaceAllStringFunc(u.EscapedFragment(), strings.ToLower)
+		}
+	}
+
+	if err := setEscapedPath(u, path); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// filterQuery removes and sorts parameters of rawQuery, keeping separators in their positions.
+// Empty parameters are removed as well.
+func (o NormalizeOptions) filterQuery(rawQuery string) string {
+	q := ParseQuery(rawQuery)
+	for i := 0; i < len(q.params); {
+		if q.params[i].raw == "" {
+			q.remove(i)
+		} else {
+			i++
+		}
+	}
+
+	if o.RemoveTrackingParams {
+		q.DelFunc(isTrackingParam)
+	}
+	if o.SortQuery {
+		q.Sort()
+	}
+	return q.String()
+}
+
+func isTrackingParam(name string) bool {
+	for _, t := range TrackingParams {
+		if strings.HasSuffix(t, "*") {
+			if strings.HasPrefix(name, t[:len(t)-1]) {
+				return true
+			}
+		} else if name == t {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseSlashes(path string) string {
+	for strings.Contains(path, "//") {
+		path = strings.Replace(path, "//", "/", -1)
+	}
+	return path
+}
+
+func removeTrailingSlash(path string) string {
+	if len(path) <= 1 {
+		return path
+	}
+	path = strings.TrimRight(path, "/")
+	if path == "" {
+		path = "/"
+	}
+	return path
+}
+
+func removeDirectoryIndex(path string) string {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return path
+	}
+
+	for _, name := range DirectoryIndexNames {
+		if strings.EqualFold(path[i+1:], name) {
+			return path[:i+1]
+		}
+	}
+	return path
+}
+
+// setEscapedPath sets u.Path and u.RawPath from an escaped path.
+func setEscapedPath(u *url.URL, escaped string) error {
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return err
+	}
+
+	u.Path = path
+	u.RawPath = ""
+	if u.EscapedPath() != escaped {
+		u.RawPath = escaped
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package urlutil
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeOptions_Normalize(t *testing.T) {
+	tests := []struct {
+		name string
+		opts NormalizeOptions
+		in   string
+		want string
+	}{
+		{
+			name: "Zero options",
+			in:   "HTTP://www.Example.com:80/a//b/index.html?b=1&a=2&utm_source=x#frag",
+			want: "http://www.example.com/a//b/index.html?b=1&a=2&utm_source=x#frag",
+		},
+		{
+			name: "Sort query",
+			opts: NormalizeOptions{SortQuery: true},
+			in:   "http://example.com/?b=1&a=2;c=3&a=1",
+			want: "http://example.com/?a=2&a=1;b=1&c=3",
+		},
+		{
+			name: "Remove empty query",
+			opts: NormalizeOptions{RemoveEmptyQuery: true},
+			in:   "http://example.com/?",
+			want: "http://example.com/",
+		},
+		{
+			name: "Keep empty query",
+			in:   "http://example.com/?",
+			want: "http://example.com/?",
+		},
+		{
+			name: "Remove fragment",
+			opts: NormalizeOptions{RemoveFragment: true},
+			in:   "http://example.com/#section",
+			want: "http://example.com/",
+		},
+		{
+			name: "Remove tracking params",
+			opts: NormalizeOptions{RemoveTrackingParams: true},
+			in:   "http://example.com/?utm_source=x&id=1&fbclid=abc&UTM_x=1&gclid=2",
+			want: "http://example.com/?id=1&UTM_x=1",
+		},
+		{
+			name: "Remove tracking params keeping separators",
+			opts: NormalizeOptions{RemoveTrackingParams: true},
+			in:   "http://example.com/?a=1;utm_source=x;b=2&&c=3",
+			want: "http://example.com/?a=1;b=2&c=3",
+		},
+		{
+			name: "Remove tracking params (all)",
+			opts: NormalizeOptions{RemoveTrackingParams: true, RemoveEmptyQuery: true},
+			in:   "http://example.com/?utm_source=x&utm_medium=y",
+			want: "http://example.com/",
+		},
+		{
+			name: "Remove repeated www",
+			opts: NormalizeOptions{RemoveWWW: true},
+			in:   "http://www.www.example.com/",
+			want: "http://example.com/",
+		},
+		{
+			name: "Remove www",
+			opts: NormalizeOptions{RemoveWWW: true},
+			in:   "http://WWW.example.com:8080/",
+			want: "http://example.com:8080/",
+		},
+		{
+			name: "Keep www of TLD",
+			opts: NormalizeOptions{RemoveWWW: true},
+			in:   "http://www.com/",
+			want: "http://www.com/",
+		},
+		{
+			name: "Remove directory index",
+			opts: NormalizeOptions{RemoveDirectoryIndex: true},
+			in:   "http://example.com/a/Index.HTML?q",
+			want: "http://example.com/a/?q",
+		},
+		{
+			name: "Keep non-index file",
+			opts: NormalizeOptions{RemoveDirectoryIndex: true},
+			in:   "http://example.com/a/index.html.bak",
+			want: "http://example.com/a/index.html.bak",
+		},
+		{
+			name: "Collapse slashes",
+			opts: NormalizeOptions{CollapseSlashes: true},
+			in:   "http://example.com//a///b/",
+			want: "http://example.com/a/b/",
+		},
+		{
+			name: "Lowercase percent-encodings",
+			opts: NormalizeOptions{PercentEncodingCase: PercentEncodingLower},
+			in:   "http://example.com/%5e%E3%81%82?q=%5E#%5E",
+			want: "http://example.com/%5e%e3%81%82?q=%5e#%5e",
+		},
+		{
+			name: "Add trailing slash",
+			opts: NormalizeOptions{TrailingSlash: TrailingSlashAdd},
+			in:   "http://example.com/a/b?q",
+			want: "http://example.com/a/b/?q",
+		},
+		{
+			name: "Remove trailing slash",
+			opts: NormalizeOptions{TrailingSlash: TrailingSlashRemove},
+			in:   "http://example.com/a/b//",
+			want: "http://example.com/a/b",
+		},
+		{
+			name: "Remove trailing slash and directory index",
+			opts: NormalizeOptions{RemoveDirectoryIndex: true, TrailingSlash: TrailingSlashRemove},
+			in:   "http://example.com/index.html/a/index.html/",
+			want: "http://example.com/index.html/a",
+		},
+		{
+			name: "Keep root slash",
+			opts: NormalizeOptions{TrailingSlash: TrailingSlashRemove},
+			in:   "http://example.com/",
+			want: "http://example.com/",
+		},
+		{
+			name: "Safe",
+			opts: SafeNormalization,
+			in:   "HTTP://www.Example.com:80/a/./b/index.html?",
+			want: "http://www.example.com/a/b/index.html",
+		},
+		{
+			name: "Aggressive",
+			opts: AggressiveNormalization,
+			in:   "HTTP://www.Example.com:80/a//b/index.html?utm_campaign=x&b=1&a=2#frag",
+			want: "http://example.com/a/b?a=2&b=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.opts.Normalize(u)
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Normalize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+var normalizeOptionsCorpus = []string{
+	"HTTP://www.Example.com:80/a//b/index.html?utm_campaign=x&b=1&a=2#frag",
+	"http://www.example.com/a//index.html/",
+	"http://www.www.example.com/index.html/index.html//",
+	"http://example.com/a/./b/../c//d/?",
+	"http://example.com/?b=1;a=2&&c=3;utm_source=x;",
+	"http://example.com/?&",
+	"http://example.com/%5e%E3%81%82/a%2fb?q=%5E#%5E",
+	"http://example.com/a/Index.HTML/?q",
+	"https://[2001:DB8::1]:443/default.aspx",
+	"http://www.com/",
+	"mailto:someone@example.com",
+}
+
+func TestNormalizeOptions_Normalize_Idempotent(t *testing.T) {
+	options := map[string]NormalizeOptions{
+		"Zero":       {},
+		"Safe":       SafeNormalization,
+		"Aggressive": AggressiveNormalization,
+		"Lower":      {PercentEncodingCase: PercentEncodingLower, TrailingSlash: TrailingSlashAdd, RemoveDirectoryIndex: true},
+	}
+	for name, opts := range options {
+		for _, in := range normalizeOptionsCorpus {
+			u, err := url.Parse(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once, err := opts.Normalize(u)
+			if err != nil {
+				t.Fatalf("%s: Normalize(%q) error = %v", name, in, err)
+			}
+			s := once.String()
+			twice, err := opts.Normalize(once)
+			if err != nil {
+				t.Fatalf("%s: Normalize(%q) error = %v", name, s, err)
+			}
+			if twice.String() != s {
+				t.Errorf("%s: Normalize(Normalize(%q)) = %v, want %v", name, in, twice, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package urlutil
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+//go:generate go run gen_publicsuffix.go -input public_suffix_list.dat -output publicsuffix.txt
+
+//go:embed publicsuffix.txt
+var publicSuffixList string
+
+type publicSuffixRule struct {
+	exception bool
+	private   bool
+}
+
+var (
+	publicSuffixRulesOnce sync.Once
+	publicSuffixRules     map[string]publicSuffixRule
+)
+
+func loadPublicSuffixRules() {
+	publicSuffixRules = map[string]publicSuffixRule{}
+
+	s := bufio.NewScanner(strings.NewReader(publicSuffixList))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+
+		var rule publicSuffixRule
+		name := fields[0]
+		if strings.HasPrefix(name, "!") {
+			rule.exception = true
+			name = name[1:]
+		}
+		rule.private = len(fields) > 1 && fields[1] == "private"
+
+		publicSuffixRules[name] = rule
+	}
+}
+
+// PublicSuffix returns the public suffix of domain, which should be in ASCII, using the embedded
+// Public Suffix List. icann reports whether the suffix is managed by ICANN, rather than privately.
+// Domains not matching any rules are considered to have the last label as their suffix.
+// https://github.com/publicsuffix/list/wiki/Format#algorithm
+func PublicSuffix(domain string) (suffix string, icann bool) {
+	publicSuffixRulesOnce.Do(loadPublicSuffixRules)
+
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	labels := strings.Split(domain, ".")
+
+	for i := range labels {
+		candidate := strings.Join(labels[i:], ".")
+
+		if rule, ok := publicSuffixRules[candidate]; ok {
+			if rule.exception {
+				// An exception rule removes the leftmost label from the matched rule
+				return strings.Join(labels[i+1:], "."), !rule.private
+			}
+			return candidate, !rule.private
+		}
+
+		if i+1 < len(labels) {
+			if rule, ok := publicSuffixRules["*."+strings.Join(labels[i+1:], ".")]; ok {
+				return candidate, !rule.private
+			}
+		}
+	}
+
+	return labels[len(labels)-1], false
+}
+
+// EffectiveTLDPlusOne returns the registrable domain of domain, that is, its public suffix and
+// the label preceding it. It returns an error if domain is a public suffix itself.
+func EffectiveTLDPlusOne(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if domain == "" || strings.HasPrefix(domain, ".") || strings.Contains(domain, "..") {
+		return "", fmt.Errorf("invalid domain %q", domain)
+	}
+
+	suffix, _ := PublicSuffix(domain)
+	if len(domain) <= len(suffix) {
+		return "", fmt.Errorf("cannot derive eTLD+1 for domain %q", domain)
+	}
+
+	i := strings.LastIndexByte(domain[:len(domain)-len(suffix)-1], '.')
+	return domain[i+1:], nil
+}
+
+// SubdomainLabels returns the labels of domain preceding its registrable domain.
+// For example, the subdomain labels of "a.b.example.co.uk" are ["a", "b"].
+func SubdomainLabels(domain string) ([]string, error) {
+	registrable, err := EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	if domain == registrable {
+		return []string{}, nil
+	}
+
+	return strings.Split(strings.TrimSuffix(domain, "."+registrable), "."), nil
+}
+
+// RegistrableDomain returns the registrable domain of the host of u after normalized by NormalizeURL.
+// For IP addresses, the address itself is returned.
+func RegistrableDomain(u *url.URL) (string, error) {
+	u, err := NormalizeURL(u)
+	if err != nil {
+		return "", err
+	}
+
+	return registrableHost(u.Hostname())
+}
+
+func registrableHost(host string) (string, error) {
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	return EffectiveTLDPlusOne(host)
+}
+
+// SameOrigin reports whether a and b have the same scheme, host and port after normalized by NormalizeURL.
+// https://html.spec.whatwg.org/multipage/browsers.html#same-origin
+func SameOrigin(a, b *url.URL) (bool, error) {
+	a, err := NormalizeURL(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = NormalizeURL(b)
+	if err != nil {
+		return false, err
+	}
+
+	return a.Scheme == b.Scheme && a.Host == b.Host, nil
+}
+
+// SameSite reports whether a and b have the same scheme and registrable domain after normalized by NormalizeURL.
+// Hosts without registrable domains, such as public suffixes, must be equal to be the same site.
+// https://html.spec.whatwg.org/multipage/browsers.html#same-site
+func SameSite(a, b *url.URL) (bool, error) {
+	a, err := NormalizeURL(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = NormalizeURL(b)
+	if err != nil {
+		return false, err
+	}
+
+	if a.Scheme != b.Scheme {
+		return false, nil
+	}
+
+	hostA, hostB := a.Hostname(), b.Hostname()
+	if hostA == hostB {
+		return true, nil
+	}
+
+	siteA, err := registrableHost(hostA)
+	if err != nil {
+		return false, nil
+	}
+	siteB, err := registrableHost(hostB)
+	if err != nil {
+		return false, nil
+	}
+
+	return siteA == siteB, nil
+}
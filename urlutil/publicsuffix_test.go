@@ -0,0 +1,140 @@
+package urlutil
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPublicSuffix(t *testing.T) {
+	tests := []struct {
+		domain string
+		suffix string
+		icann  bool
+	}{
+		{"example.com", "com", true},
+		{"b.example.com", "com", true},
+		{"example.co.uk", "co.uk", true},
+		{"city.kobe.jp", "kobe.jp", true},
+		{"c.kobe.jp", "c.kobe.jp", true},
+		{"www.ck", "ck", true},
+		{"test.ck", "test.ck", true},
+		{"foo.github.io", "github.io", false},
+		{"Example.COM.", "com", true},
+		{"example.unknowntld", "unknowntld", false},
+		{"example.xn--55qx5d.cn", "xn--55qx5d.cn", true},
+	}
+
+	for _, test := range tests {
+		suffix, icann := PublicSuffix(test.domain)
+		if suffix != test.suffix || icann != test.icann {
+			t.Errorf("PublicSuffix(%q) = (%q, %v), want (%q, %v)", test.domain, suffix, icann, test.suffix, test.icann)
+		}
+	}
+}
+
+func TestEffectiveTLDPlusOne(t *testing.T) {
+	tests := []struct {
+		domain  string
+		want    string
+		wantErr bool
+	}{
+		{domain: "example.com", want: "example.com"},
+		{domain: "b.example.com", want: "example.com"},
+		{domain: "a.b.example.co.uk", want: "example.co.uk"},
+		{domain: "city.kobe.jp", want: "city.kobe.jp"},
+		{domain: "www.city.kobe.jp", want: "city.kobe.jp"},
+		{domain: "c.kobe.jp", wantErr: true},
+		{domain: "b.c.kobe.jp", want: "b.c.kobe.jp"},
+		{domain: "www.ck", want: "www.ck"},
+		{domain: "test.ck", wantErr: true},
+		{domain: "github.io", wantErr: true},
+		{domain: "motemen.github.io", want: "motemen.github.io"},
+		{domain: "com", wantErr: true},
+		{domain: "", wantErr: true},
+		{domain: "a..example.com", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := EffectiveTLDPlusOne(test.domain)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("EffectiveTLDPlusOne(%q) = %q, want error", test.domain, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("EffectiveTLDPlusOne(%q): %v", test.domain, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("EffectiveTLDPlusOne(%q) = %q, want %q", test.domain, got, test.want)
+		}
+	}
+}
+
+func TestSubdomainLabels(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   []string
+	}{
+		{"example.com", []string{}},
+		{"www.example.com", []string{"www"}},
+		{"a.b.example.co.uk", []string{"a", "b"}},
+		{"www.city.kobe.jp", []string{"www"}},
+	}
+
+	for _, test := range tests {
+		got, err := SubdomainLabels(test.domain)
+		if err != nil {
+			t.Errorf("SubdomainLabels(%q): %v", test.domain, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SubdomainLabels(%q) = %q, want %q", test.domain, got, test.want)
+		}
+	}
+}
+
+func TestSameOriginAndSameSite(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		sameOrigin bool
+		sameSite   bool
+	}{
+		{"http://example.com/a", "HTTP://EXAMPLE.COM:80/b", true, true},
+		{"http://example.com/", "https://example.com/", false, false},
+		{"http://example.com/", "http://example.com:8080/", false, true},
+		{"https://www.example.co.uk/", "https://example.co.uk/", false, true},
+		{"https://a.github.io/", "https://b.github.io/", false, false},
+		{"https://github.io/", "https://github.io/", true, true},
+		{"https://例え.jp/", "https://www.xn--r8jz45g.jp/", false, true},
+		{"http://192.0.2.1/", "http://192.0.2.1:8080/", false, true},
+		{"http://192.0.2.1/", "http://192.0.2.2/", false, false},
+	}
+
+	for _, test := range tests {
+		a, err := url.Parse(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := url.Parse(test.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sameOrigin, err := SameOrigin(a, b)
+		if err != nil {
+			t.Errorf("SameOrigin(%q, %q): %v", test.a, test.b, err)
+		} else if sameOrigin != test.sameOrigin {
+			t.Errorf("SameOrigin(%q, %q) = %v, want %v", test.a, test.b, sameOrigin, test.sameOrigin)
+		}
+
+		sameSite, err := SameSite(a, b)
+		if err != nil {
+			t.Errorf("SameSite(%q, %q): %v", test.a, test.b, err)
+		} else if sameSite != test.sameSite {
+			t.Errorf("SameSite(%q, %q) = %v, want %v", test.a, test.b, sameSite, test.sameSite)
+		}
+	}
+}
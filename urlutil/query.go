@@ -0,0 +1,220 @@
+package urlutil
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Query is an ordered list of query parameters parsed from url.URL.RawQuery.
+// Unlike url.Values, it keeps the order, the encodings and the separators of parameters,
+// so that String returns the original query unless modified. Parameters are separated by
+// "&" or ";", as NormalizeURL accepts.
+//
+// Keys and values given to and returned from methods are unescaped. Parameters added or
+// modified are encoded by url.QueryEscape.
+type Query struct {
+	params []queryParam
+	// sep is the separator used for new parameters
+	sep string
+}
+
+type queryParam struct {
+	sep string // separator preceding the parameter, empty for the first one
+	raw string
+	key string
+}
+
+// ParseQuery parses a raw query. Malformed percent-encodings are kept as they are.
+func ParseQuery(rawQuery string) *Query {
+	q := &Query{sep: "&"}
+	if rawQuery == "" {
+		return q
+	}
+
+	sep := ""
+	for {
+		i := strings.IndexAny(rawQuery, "&;")
+		raw := rawQuery
+		if i >= 0 {
+			raw = rawQuery[:i]
+		}
+
+		rawKey, _ := splitQueryParam(raw)
+		q.params = append(q.params, queryParam{sep: sep, raw: raw, key: unescapeQuery(rawKey)})
+
+		if i < 0 {
+			break
+		}
+		sep, rawQuery = rawQuery[i:i+1], rawQuery[i+1:]
+		q.sep = sep
+	}
+
+	return q
+}
+
+// QueryOf parses the raw query of u.
+func QueryOf(u *url.URL) *Query {
+	return ParseQuery(u.RawQuery)
+}
+
+func splitQueryParam(raw string) (rawKey, rawValue string) {
+	if i := strings.IndexByte(raw, '='); i >= 0 {
+		return raw[:i], raw[i+1:]
+	}
+	return raw, ""
+}
+
+func unescapeQuery(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// String returns the raw query, which is the same as the parsed one if q is not modified.
+func (q *Query) String() string {
+	var b strings.Builder
+	for _, p := range q.params {
+		b.WriteString(p.sep)
+		b.WriteString(p.raw)
+	}
+	return b.String()
+}
+
+// Len returns the number of parameters in q, including empty ones like between "&&".
+func (q *Query) Len() int {
+	return len(q.params)
+}
+
+// Keys returns the unique keys of parameters in the order of appearance.
+func (q *Query) Keys() []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, p := range q.params {
+		if p.raw == "" || seen[p.key] {
+			continue
+		}
+		seen[p.key] = true
+		keys = append(keys, p.key)
+	}
+	return keys
+}
+
+// Has reports whether q has a parameter of key.
+func (q *Query) Has(key string) bool {
+	return q.index(key, 0) >= 0
+}
+
+// Get returns the value of the first parameter of key.
+func (q *Query) Get(key string) (string, bool) {
+	i := q.index(key, 0)
+	if i < 0 {
+		return "", false
+	}
+	return q.params[i].value(), true
+}
+
+// GetAll returns the values of all the parameters of key.
+func (q *Query) GetAll(key string) []string {
+	var values []string
+	for i := q.index(key, 0); i >= 0; i = q.index(key, i+1) {
+		values = append(values, q.params[i].value())
+	}
+	return values
+}
+
+// Set replaces the value of the first parameter of key and removes the others.
+// The parameter is appended if q does not have key.
+func (q *Query) Set(key, value string) {
+	i := q.index(key, 0)
+	if i < 0 {
+		q.Add(key, value)
+		return
+	}
+
+	q.params[i].raw = encodeQueryParam(key, value)
+	for j := q.index(key, i+1); j >= 0; j = q.index(key, j) {
+		q.remove(j)
+	}
+}
+
+// Add appends a parameter.
+func (q *Query) Add(key, value string) {
+	sep := ""
+	if len(q.params) > 0 {
+		sep = q.sep
+	}
+	q.params = append(q.params, queryParam{sep: sep, raw: encodeQueryParam(key, value), key: key})
+}
+
+// Del removes all the parameters of key.
+func (q *Query) Del(key string) {
+	q.DelFunc(func(k string) bool { return k == key })
+}
+
+// DelFunc removes all the parameters whose keys satisfy f.
+func (q *Query) DelFunc(f func(key string) bool) {
+	for i := 0; i < len(q.params); {
+		if q.params[i].raw != "" && f(q.params[i].key) {
+			q.remove(i)
+		} else {
+			i++
+		}
+	}
+}
+
+// Sort sorts parameters by their keys, keeping the order of parameters of the same key.
+// Separators are kept in their positions.
+func (q *Query) Sort() {
+	seps := make([]string, len(q.params))
+	for i, p := range q.params {
+		seps[i] = p.sep
+	}
+
+	sort.SliceStable(q.params, func(i, j int) bool {
+		return q.params[i].key < q.params[j].key
+	})
+
+	for i := range q.params {
+		q.params[i].sep = seps[i]
+	}
+}
+
+// Values returns q as url.Values.
+func (q *Query) Values() url.Values {
+	values := url.Values{}
+	for _, p := range q.params {
+		if p.raw == "" {
+			continue
+		}
+		values[p.key] = append(values[p.key], p.value())
+	}
+	return values
+}
+
+// index returns the index of the first parameter of key at or after start, or -1.
+func (q *Query) index(key string, start int) int {
+	for i := start; i < len(q.params); i++ {
+		if q.params[i].raw != "" && q.params[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (q *Query) remove(i int) {
+	if i == 0 && len(q.params) > 1 {
+		q.params[1].sep = ""
+	}
+	q.params = append(q.params[:i], q.params[i+1:]...)
+}
+
+func (p queryParam) value() string {
+	_, rawValue := splitQueryParam(p.raw)
+	return unescapeQuery(rawValue)
+}
+
+func encodeQueryParam(key, value string) string {
+	return url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,102 @@
+package urlutil
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery_RoundTrip(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"a=1",
+		"b=2&a=1&b=3",
+		"a=1;b=2&c",
+		"a=1&&b=2&",
+		"q=%e3%81%82+%41&r=%G",
+		"=x&a==b",
+	} {
+		if got := ParseQuery(raw).String(); got != raw {
+			t.Errorf("ParseQuery(%q).String() = %q", raw, got)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	q := ParseQuery("b=2;a=1&b=%33&c&d=%41+b&e=%G")
+
+	if got, want := q.Keys(), []string{"b", "a", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q, want %q", got, want)
+	}
+	if got, want := q.GetAll("b"), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAll(b) = %q, want %q", got, want)
+	}
+	if v, ok := q.Get("c"); !ok || v != "" {
+		t.Errorf("Get(c) = (%q, %v)", v, ok)
+	}
+	if v, _ := q.Get("d"); v != "A b" {
+		t.Errorf("Get(d) = %q", v)
+	}
+	if v, _ := q.Get("e"); v != "%G" {
+		t.Errorf("Get(e) = %q", v)
+	}
+	if _, ok := q.Get("x"); ok {
+		t.Error("Get(x) should not be found")
+	}
+
+	q.Set("a", "x y")
+	if got, want := q.String(), "b=2;a=x+y&b=%33&c&d=%41+b&e=%G"; got != want {
+		t.Errorf("after Set(a): %q, want %q", got, want)
+	}
+
+	q.Set("b", "4")
+	if got, want := q.String(), "b=4;a=x+y&c&d=%41+b&e=%G"; got != want {
+		t.Errorf("after Set(b): %q, want %q", got, want)
+	}
+
+	q.Add("f", "&")
+	if got, want := q.String(), "b=4;a=x+y&c&d=%41+b&e=%G&f=%26"; got != want {
+		t.Errorf("after Add(f): %q, want %q", got, want)
+	}
+
+	q.Del("b")
+	if got, want := q.String(), "a=x+y&c&d=%41+b&e=%G&f=%26"; got != want {
+		t.Errorf("after Del(b): %q, want %q", got, want)
+	}
+
+	q.Sort()
+	if got, want := q.String(), "a=x+y&c&d=%41+b&e=%G&f=%26"; got != want {
+		t.Errorf("after Sort: %q, want %q", got, want)
+	}
+
+	if got, want := q.Values(), (url.Values{"a": {"x y"}, "c": {""}, "d": {"A b"}, "e": {"%G"}, "f": {"&"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+}
+
+func TestQuery_Separators(t *testing.T) {
+	q := ParseQuery("a=1;b=2")
+	q.Add("c", "3")
+	if got, want := q.String(), "a=1;b=2;c=3"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	q = ParseQuery("")
+	q.Set("a", "1")
+	q.Add("b", "2")
+	if got, want := q.String(), "a=1&b=2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	q = ParseQuery("z=1&y=2;x=3")
+	q.Sort()
+	if got, want := q.String(), "x=3&y=2;z=1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	q = ParseQuery("utm_source=a&id=1&&utm_medium=b")
+	q.DelFunc(isTrackingParam)
+	if got, want := q.String(), "id=1&"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
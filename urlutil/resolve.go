@@ -0,0 +1,168 @@
+package urlutil
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Resolve resolves a URI reference ref against base by the algorithm of RFC 3986 section 5.2.
+// Unlike url.URL.ResolveReference, it keeps percent-encodings of paths as is and does not normalize
+// the result, so that the result is exactly what the RFC specifies.
+// https://datatracker.ietf.org/doc/html/rfc3986#section-5.2
+func Resolve(base, ref *url.URL) (*url.URL, error) {
+	t := &url.URL{}
+
+	var path string
+	if ref.Scheme != "" {
+		t.Scheme = ref.Scheme
+		t.Opaque = ref.Opaque
+		setAuthority(t, ref)
+		path = removeDotSegments(ref.EscapedPath())
+		setQuery(t, ref)
+	} else {
+		if hasAuthority(ref) {
+			setAuthority(t, ref)
+			path = removeDotSegments(ref.EscapedPath())
+			setQuery(t, ref)
+		} else {
+			refPath := ref.EscapedPath()
+			if refPath == "" {
+				path = base.EscapedPath()
+				if hasQuery(ref) {
+					setQuery(t, ref)
+				} else {
+					setQuery(t, base)
+				}
+			} else {
+				if strings.HasPrefix(refPath, "/") {
+					path = removeDotSegments(refPath)
+				} else {
+					path = removeDotSegments(mergePaths(base, refPath))
+				}
+				setQuery(t, ref)
+			}
+			setAuthority(t, base)
+		}
+		t.Scheme = base.Scheme
+	}
+
+	t.Fragment = ref.Fragment
+	t.RawFragment = ref.RawFragment
+
+	if err := setEscapedPath(t, path); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Relativize returns the shortest URI reference which resolves to target against base by Resolve.
+// It returns a clone of target if they differ in schemes.
+func Relativize(base, target *url.URL) (*url.URL, error) {
+	if !strings.EqualFold(base.Scheme, target.Scheme) || target.Opaque != "" || base.Opaque != "" {
+		return CloneURL(target), nil
+	}
+
+	ref := &url.URL{}
+	ref.Fragment = target.Fragment
+	ref.RawFragment = target.RawFragment
+
+	basePath, targetPath := base.EscapedPath(), target.EscapedPath()
+
+	if base.Host != target.Host || base.User.String() != target.User.String() || (targetPath == "" && basePath != "") {
+		// Network-path reference
+		setAuthority(ref, target)
+		setQuery(ref, target)
+		if err := setEscapedPath(ref, targetPath); err != nil {
+			return nil, err
+		}
+		return ref, nil
+	}
+
+	if basePath == targetPath {
+		if hasQuery(target) && (!hasQuery(base) || base.RawQuery != target.RawQuery) {
+			setQuery(ref, target)
+			return ref, nil
+		}
+		if hasQuery(target) == hasQuery(base) {
+			return ref, nil
+		}
+	}
+
+	path := relativePath(base, basePath, targetPath)
+	if strings.HasPrefix(targetPath, "/") && !strings.HasPrefix(targetPath, "//") && len(targetPath) < len(path) {
+		path = targetPath
+	}
+
+	setQuery(ref, target)
+	if err := setEscapedPath(ref, path); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// relativePath returns a relative-path reference from the directory of basePath to targetPath.
+func relativePath(base *url.URL, basePath, targetPath string) string {
+	if basePath == "" && hasAuthority(base) {
+		basePath = "/"
+	}
+
+	baseDir := strings.Split(basePath, "/")
+	baseDir = baseDir[:len(baseDir)-1]
+	targetSegs := strings.Split(targetPath, "/")
+
+	common := 0
+	for common < len(baseDir) && common < len(targetSegs)-1 && baseDir[common] == targetSegs[common] {
+		common++
+	}
+
+	var b strings.Builder
+	for i := common; i < len(baseDir); i++ {
+		b.WriteString("../")
+	}
+	b.WriteString(strings.Join(targetSegs[common:], "/"))
+
+	path := b.String()
+	rest := targetSegs[common:]
+	switch {
+	case path == "":
+		path = "."
+	case strings.HasSuffix(path, "../"):
+		// ".." resolves to the directory with a trailing slash
+		path = strings.TrimSuffix(path, "/")
+	case strings.HasPrefix(path, "/"), strings.Contains(rest[0], ":") && !strings.HasPrefix(path, "../"):
+		// Must not be taken as an absolute path or a scheme
+		path = "./" + path
+	}
+	return path
+}
+
+// mergePaths merges a relative-path reference with the path of base.
+// https://datatracker.ietf.org/doc/html/rfc3986#section-5.2.3
+func mergePaths(base *url.URL, refPath string) string {
+	basePath := base.EscapedPath()
+	if hasAuthority(base) && basePath == "" {
+		return "/" + refPath
+	}
+
+	i := strings.LastIndexByte(basePath, '/')
+	return basePath[:i+1] + refPath
+}
+
+func hasAuthority(u *url.URL) bool {
+	return u.Host != "" || u.User != nil
+}
+
+func hasQuery(u *url.URL) bool {
+	return u.RawQuery != "" || u.ForceQuery
+}
+
+func setAuthority(dst, src *url.URL) {
+	dst.Host = src.Host
+	dst.User = src.User
+}
+
+func setQuery(dst, src *url.URL) {
+	dst.RawQuery = src.RawQuery
+	dst.ForceQuery = src.ForceQuery
+}
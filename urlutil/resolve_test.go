@@ -0,0 +1,172 @@
+package urlutil
+
+import (
+	"net/url"
+	"testing"
+)
+
+// Examples from RFC 3986 section 5.4
+var resolveTests = []struct {
+	ref  string
+	want string
+}{
+	// Normal examples
+	{"g:h", "g:h"},
+	{"g", "http://a/b/c/g"},
+	{"./g", "http://a/b/c/g"},
+	{"g/", "http://a/b/c/g/"},
+	{"/g", "http://a/g"},
+	{"//g", "http://g"},
+	{"?y", "http://a/b/c/d;p?y"},
+	{"g?y", "http://a/b/c/g?y"},
+	{"#s", "http://a/b/c/d;p?q#s"},
+	{"g#s", "http://a/b/c/g#s"},
+	{"g?y#s", "http://a/b/c/g?y#s"},
+	{";x", "http://a/b/c/;x"},
+	{"g;x", "http://a/b/c/g;x"},
+	{"g;x?y#s", "http://a/b/c/g;x?y#s"},
+	{"", "http://a/b/c/d;p?q"},
+	{".", "http://a/b/c/"},
+	{"./", "http://a/b/c/"},
+	{"..", "http://a/b/"},
+	{"../", "http://a/b/"},
+	{"../g", "http://a/b/g"},
+	{"../..", "http://a/"},
+	{"../../", "http://a/"},
+	{"../../g", "http://a/g"},
+
+	// Abnormal examples
+	{"../../../g", "http://a/g"},
+	{"../../../../g", "http://a/g"},
+	{"/./g", "http://a/g"},
+	{"/../g", "http://a/g"},
+	{"g.", "http://a/b/c/g."},
+	{".g", "http://a/b/c/.g"},
+	{"g..", "http://a/b/c/g.."},
+	{"..g", "http://a/b/c/..g"},
+	{"./../g", "http://a/b/g"},
+	{"./g/.", "http://a/b/c/g/"},
+	{"g/./h", "http://a/b/c/g/h"},
+	{"g/../h", "http://a/b/c/h"},
+	{"g;x=1/./y", "http://a/b/c/g;x=1/y"},
+	{"g;x=1/../y", "http://a/b/c/y"},
+	{"g?y/./x", "http://a/b/c/g?y/./x"},
+	{"g?y/../x", "http://a/b/c/g?y/../x"},
+	{"g#s/./x", "http://a/b/c/g#s/./x"},
+	{"g#s/../x", "http://a/b/c/g#s/../x"},
+	{"http:g", "http:g"},
+}
+
+const resolveTestBase = "http://a/b/c/d;p?q"
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestResolve(t *testing.T) {
+	base := mustParseURL(t, resolveTestBase)
+
+	for _, test := range resolveTests {
+		got, err := Resolve(base, mustParseURL(t, test.ref))
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", test.ref, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Resolve(%q) = %q, want %q", test.ref, got, test.want)
+		}
+	}
+}
+
+func TestResolve_EncodedPath(t *testing.T) {
+	base := mustParseURL(t, "http://a/b%2Fc/d")
+	got, err := Resolve(base, mustParseURL(t, "e%2Ff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://a/b%2Fc/e%2Ff"; got.String() != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRelativize(t *testing.T) {
+	tests := []struct {
+		base   string
+		target string
+		want   string
+	}{
+		{resolveTestBase, "http://a/b/c/g", "g"},
+		{resolveTestBase, "http://a/b/c/", "."},
+		{resolveTestBase, "http://a/b/", ".."},
+		{resolveTestBase, "http://a/b/g", "../g"},
+		{resolveTestBase, "http://a/g", "/g"},
+		{resolveTestBase, "http://a/", "/"},
+		{resolveTestBase, "http://g/x", "//g/x"},
+		{resolveTestBase, "https://a/b/c/g", "https://a/b/c/g"},
+		{resolveTestBase, "http://a/b/c/d;p?q", ""},
+		{resolveTestBase, "http://a/b/c/d;p?y", "?y"},
+		{resolveTestBase, "http://a/b/c/d;p", "d;p"},
+		{resolveTestBase, "http://a/b/c/d;p?q#s", "#s"},
+		{resolveTestBase, "http://a/b/c/g:h", "./g:h"},
+		{resolveTestBase, "http://a/b/c/d/e/f", "d/e/f"},
+		{"http://a/bb/cc/d/e/f", "http://a/bb/cc/x", "../../x"},
+		{"http://a/b/c/d/e/f", "http://a/b/x", "/b/x"},
+		{"http://a/b/c/d/e/f", "http://a/x", "/x"},
+		{"http://a", "http://a/x", "x"},
+		{"http://a/archive/2021/index.html", "http://a/archive/2021/img/logo.png?v=1", "img/logo.png?v=1"},
+	}
+
+	for _, test := range tests {
+		base := mustParseURL(t, test.base)
+		target := mustParseURL(t, test.target)
+
+		got, err := Relativize(base, target)
+		if err != nil {
+			t.Errorf("Relativize(%q, %q): %v", test.base, test.target, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Relativize(%q, %q) = %q, want %q", test.base, test.target, got, test.want)
+		}
+
+		resolved, err := Resolve(base, got)
+		if err != nil {
+			t.Errorf("Resolve(%q, %q): %v", test.base, got, err)
+			continue
+		}
+		if resolved.String() != test.target {
+			t.Errorf("Resolve(%q, Relativize(%q)) = %q", test.base, test.target, resolved)
+		}
+	}
+}
+
+func TestRelativize_RoundTrip(t *testing.T) {
+	base := mustParseURL(t, resolveTestBase)
+
+	for _, test := range resolveTests {
+		target := mustParseURL(t, test.want)
+
+		ref, err := Relativize(base, target)
+		if err != nil {
+			t.Errorf("Relativize(%q): %v", test.want, err)
+			continue
+		}
+
+		got, err := Resolve(base, ref)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", ref, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("Relativize(%q) = %q, which resolves to %q", test.want, ref, got)
+		}
+		if len(ref.String()) > len(test.ref) && test.ref != "" {
+			t.Errorf("Relativize(%q) = %q, longer than %q", test.want, ref, test.ref)
+		}
+	}
+}
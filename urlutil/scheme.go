@@ -0,0 +1,251 @@
+package urlutil
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SchemeNormalizer normalizes URLs of a scheme in place of the generic normalization of NormalizeURL.
+// It is given a clone of the URL with the scheme lowercased.
+type SchemeNormalizer func(u *url.URL) (*url.URL, error)
+
+var (
+	schemesMu sync.RWMutex
+
+	defaultPorts = map[string]string{
+		"https": "443",
+		"http":  "80",
+		"ws":    "80",
+		"wss":   "443",
+		"ftp":   "21",
+		"git":   "9418",
+	}
+
+	schemeNormalizers = map[string]SchemeNormalizer{
+		"mailto": normalizeMailtoURL,
+		"data":   normalizeDataURL,
+		"urn":    normalizeURN,
+	}
+)
+
+// RegisterDefaultPort registers the default port of scheme, which NormalizeURL removes from URLs.
+// An empty port unregisters the existing one.
+//
+// Default ports of http, https, ws, wss, ftp and git are registered by default.
+func RegisterDefaultPort(scheme, port string) {
+	scheme = strings.ToLower(scheme)
+
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+
+	if port == "" {
+		delete(defaultPorts, scheme)
+	} else {
+		defaultPorts[scheme] = port
+	}
+}
+
+// RegisterSchemeNormalizer registers the normalizer of scheme used by NormalizeURL.
+// A nil normalize unregisters the existing one.
+//
+// Normalizers of mailto, data and urn are registered by default.
+func RegisterSchemeNormalizer(scheme string, normalize SchemeNormalizer) {
+	scheme = strings.ToLower(scheme)
+
+	schemesMu.Lock()
+	defer schemesMu.Unlock()
+
+	if normalize == nil {
+		delete(schemeNormalizers, scheme)
+	} else {
+		schemeNormalizers[scheme] = normalize
+	}
+}
+
+// DefaultPort returns the default port of scheme registered by RegisterDefaultPort.
+func DefaultPort(scheme string) (string, bool) {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+
+	port, ok := defaultPorts[strings.ToLower(scheme)]
+	return port, ok
+}
+
+func schemeNormalizer(scheme string) SchemeNormalizer {
+	schemesMu.RLock()
+	defer schemesMu.RUnlock()
+
+	return schemeNormalizers[scheme]
+}
+
+// upperPercentEncodings makes percent-encodings in s upper case, escaping invalid ones.
+func upperPercentEncodings(s string) string {
+	return rxPercentEncoding.ReplaceAllStringFunc(escapeInvalidPercents(s), strings.ToUpper)
+}
+
+// normalizeOpaque normalizes percent-encodings of the components of an opaque URL.
+func normalizeOpaque(u *url.URL, opaque string) *url.URL {
+	u.Opaque = opaque
+	u.RawQuery = upperPercentEncodings(u.RawQuery)
+	if u.Fragment != "" {
+		u.RawFragment = upperPercentEncodings(u.EscapedFragment())
+	}
+	return u
+}
+
+// normalizeMailtoURL normalizes domains of addresses in mailto: URLs.
+// https://datatracker.ietf.org/doc/html/rfc6068
+func normalizeMailtoURL(u *url.URL) (*url.URL, error) {
+	if u.Opaque == "" {
+		return normalizeOpaque(u, u.Opaque), nil
+	}
+
+	addrs := strings.Split(u.Opaque, ",")
+	for i, addr := range addrs {
+		at := strings.LastIndexByte(addr, '@')
+		if at < 0 {
+			addrs[i] = upperPercentEncodings(addr)
+			continue
+		}
+
+		domain, err := url.PathUnescape(addr[at+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid mailto address %q: %w", addr, err)
+		}
+		domain, err = normalizeHost(domain)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mailto address %q: %w", addr, err)
+		}
+
+		addrs[i] = upperPercentEncodings(addr[:at]) + "@" + domain
+	}
+
+	return normalizeOpaque(u, strings.Join(addrs, ",")), nil
+}
+
+// normalizeDataURL normalizes media types of data: URLs and encodes base64 data in the canonical form.
+// The default media type "text/plain;charset=US-ASCII" is omitted.
+// https://datatracker.ietf.org/doc/html/rfc2397
+func normalizeDataURL(u *url.URL) (*url.URL, error) {
+	comma := strings.IndexByte(u.Opaque, ',')
+	if comma < 0 {
+		return nil, fmt.Errorf("invalid data URL: no comma")
+	}
+
+	mediaType, data := u.Opaque[:comma], u.Opaque[comma+1:]
+
+	isBase64 := false
+	if i := strings.LastIndexByte(mediaType, ';'); i >= 0 && strings.EqualFold(strings.TrimSpace(mediaType[i+1:]), "base64") {
+		isBase64 = true
+		mediaType = mediaType[:i]
+	}
+
+	mediaType, err := normalizeDataMediaType(mediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	if isBase64 {
+		decoded, err := url.PathUnescape(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URL: %w", err)
+		}
+		b, err := decodeBase64Loosely(decoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URL: %w", err)
+		}
+		data = base64.StdEncoding.EncodeToString(b)
+		mediaType += ";base64"
+	} else {
+		data = upperPercentEncodings(data)
+	}
+
+	return normalizeOpaque(u, mediaType+","+data), nil
+}
+
+func normalizeDataMediaType(mediaType string) (string, error) {
+	mediaType = strings.TrimSpace(mediaType)
+	if mediaType == "" {
+		return "", nil
+	}
+
+	unescaped, err := url.PathUnescape(mediaType)
+	if err != nil {
+		return "", fmt.Errorf("invalid data URL media type %q: %w", mediaType, err)
+	}
+
+	// ";charset=..." is a shorthand for "text/plain;charset=..."
+	if strings.HasPrefix(unescaped, ";") {
+		unescaped = "text/plain" + unescaped
+	}
+
+	typ, params, err := mime.ParseMediaType(unescaped)
+	if err != nil {
+		return "", fmt.Errorf("invalid data URL media type %q: %w", mediaType, err)
+	}
+	if charset, ok := params["charset"]; ok {
+		params["charset"] = strings.ToLower(charset)
+	}
+
+	if typ == "text/plain" && len(params) == 1 && params["charset"] == "us-ascii" {
+		return "", nil
+	}
+
+	// FormatMediaType separates parameters with "; "
+	formatted := strings.Replace(mime.FormatMediaType(typ, params), "; ", ";", -1)
+	return escapeDataMediaType(formatted), nil
+}
+
+// escapeDataMediaType percent-encodes characters in a formatted media type which are not allowed in URLs.
+func escapeDataMediaType(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ' ', '"', ',', '%', '<', '>', '\\', '^', '`', '{', '|', '}':
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// decodeBase64Loosely decodes base64 ignoring whitespaces, missing paddings and the URL-safe alphabet.
+func decodeBase64Loosely(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\f', '\r', '=':
+			return -1
+		case '-':
+			return '+'
+		case '_':
+			return '/'
+		}
+		return r
+	}, s)
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+var rxURNNID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,30}[A-Za-z0-9]$`)
+
+// normalizeURN lowercases namespace identifiers of URNs, which are case-insensitive.
+// https://datatracker.ietf.org/doc/html/rfc8141#section-3
+func normalizeURN(u *url.URL) (*url.URL, error) {
+	colon := strings.IndexByte(u.Opaque, ':')
+	if colon < 0 {
+		return nil, fmt.Errorf("invalid URN %q", u.Opaque)
+	}
+
+	nid, nss := u.Opaque[:colon], u.Opaque[colon+1:]
+	if !rxURNNID.MatchString(nid) || nss == "" {
+		return nil, fmt.Errorf("invalid URN %q", u.Opaque)
+	}
+
+	return normalizeOpaque(u, strings.ToLower(nid)+":"+upperPercentEncodings(nss)), nil
+}
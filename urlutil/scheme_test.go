@@ -0,0 +1,124 @@
+package urlutil
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL_Schemes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "MAILTO:Alice@Example.COM", want: "mailto:Alice@example.com"},
+		{in: "mailto:a@B.example,b@%E4%BE%8B.JP?subject=Hello%20World%3f", want: "mailto:a@b.example,b@xn--fsq.jp?subject=Hello%20World%3F"},
+		{in: "mailto:?to=a@b.example", want: "mailto:?to=a@b.example"},
+		{in: "urn:ISBN:0451450523", want: "urn:isbn:0451450523"},
+		{in: "URN:example:A%2fb", want: "urn:example:A%2Fb"},
+		{in: "urn:x:", wantErr: true},
+		{in: "urn:-x:foo", wantErr: true},
+		{in: "data:,Hello%2c%20World", want: "data:,Hello%2C%20World"},
+		{in: "data:text/plain;charset=US-ASCII,Hello", want: "data:,Hello"},
+		{in: "data:;charset=utf-8,Hello", want: "data:text/plain;charset=utf-8,Hello"},
+		{in: "data:Text/HTML;Charset=UTF-8;base64,PGI%2BaGk8L2I-", want: "data:text/html;charset=utf-8;base64,PGI+aGk8L2I+"},
+		{in: "data:image/png;BASE64,iVBORw0K GgoAAAANSUhEUg", want: "data:image/png;base64,iVBORw0KGgoAAAANSUhEUg=="},
+		{in: "data:text/plain;base64,!!!", wantErr: true},
+		{in: "data:text/plain", wantErr: true},
+		{in: "TEL:+1-201-555-0123", want: "tel:+1-201-555-0123"},
+		{in: "ws://example.com:80/chat", want: "ws://example.com/chat"},
+		{in: "wss://example.com:443/chat", want: "wss://example.com/chat"},
+		{in: "ftp://example.com:21/pub", want: "ftp://example.com/pub"},
+		{in: "git://example.com:9418/repo.git", want: "git://example.com/repo.git"},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := NormalizeURL(u)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("NormalizeURL(%q) = %q, want error", test.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NormalizeURL(%q): %v", test.in, err)
+			continue
+		}
+		if got.String() != test.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestRegisterDefaultPort(t *testing.T) {
+	if port, ok := DefaultPort("git"); !ok || port != "9418" {
+		t.Errorf("DefaultPort(git) = (%q, %v)", port, ok)
+	}
+
+	defer RegisterDefaultPort("x-port", "")
+	RegisterDefaultPort("X-Port", "8080")
+
+	if port, ok := DefaultPort("x-port"); !ok || port != "8080" {
+		t.Errorf("DefaultPort(x-port) = (%q, %v)", port, ok)
+	}
+
+	for in, want := range map[string]string{
+		"git://example.com:9418/repo.git": "git://example.com/repo.git",
+		"x-port://example.com:8080/":      "x-port://example.com/",
+	} {
+		u, _ := url.Parse(in)
+		got, err := NormalizeURL(u)
+		if err != nil {
+			t.Errorf("NormalizeURL(%q): %v", in, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	RegisterDefaultPort("x-port", "")
+	if _, ok := DefaultPort("x-port"); ok {
+		t.Error("x-port should be unregistered")
+	}
+}
+
+func TestRegisterSchemeNormalizer(t *testing.T) {
+	defer RegisterSchemeNormalizer("x-upper", nil)
+	defer RegisterSchemeNormalizer("http", nil)
+
+	RegisterSchemeNormalizer("x-upper", func(u *url.URL) (*url.URL, error) {
+		u.Opaque = strings.ToUpper(u.Opaque)
+		return u, nil
+	})
+	// Registering a normalizer leaves the default port as it is
+	RegisterSchemeNormalizer("HTTP", func(u *url.URL) (*url.URL, error) {
+		u.Host = strings.TrimSuffix(strings.ToLower(u.Host), ":80")
+		return u, nil
+	})
+
+	if port, ok := DefaultPort("http"); !ok || port != "80" {
+		t.Errorf("DefaultPort(http) = (%q, %v)", port, ok)
+	}
+
+	for in, want := range map[string]string{
+		"X-Upper:abc":           "x-upper:ABC",
+		"http://EXAMPLE.com:80": "http://example.com",
+	} {
+		u, _ := url.Parse(in)
+		got, err := NormalizeURL(u)
+		if err != nil {
+			t.Errorf("NormalizeURL(%q): %v", in, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
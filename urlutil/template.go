@@ -0,0 +1,623 @@
+package urlutil
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Template is a URI Template defined in RFC 6570, supporting all of the four levels.
+// https://datatracker.ietf.org/doc/html/rfc6570
+//
+// Variables are given as a map whose values are string, []string or map[string]string.
+// Other scalar values are formatted with fmt.Sprint. Nil values, empty lists and empty maps
+// are undefined. Keys of maps are expanded in the sorted order.
+type Template struct {
+	raw   string
+	parts []templatePart
+
+	matchOnce sync.Once
+	rx        *regexp.Regexp
+}
+
+type templatePart struct {
+	literal string
+	expr    *templateExpr
+}
+
+type templateExpr struct {
+	op   templateOp
+	vars []templateVar
+}
+
+type templateVar struct {
+	name    string
+	prefix  int
+	explode bool
+}
+
+type templateOp struct {
+	char          byte
+	first         string
+	sep           string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+var templateOps = map[byte]templateOp{
+	0:   {first: "", sep: ","},
+	'+': {char: '+', first: "", sep: ",", allowReserved: true},
+	'#': {char: '#', first: "#", sep: ",", allowReserved: true},
+	'.': {char: '.', first: ".", sep: "."},
+	'/': {char: '/', first: "/", sep: "/"},
+	';': {char: ';', first: ";", sep: ";", named: true},
+	'?': {char: '?', first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {char: '&', first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// ParseTemplate parses a URI Template.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{raw: s}
+
+	rest := s
+	for rest != "" {
+		i := strings.IndexAny(rest, "{}")
+		if i < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if rest[i] == '}' {
+			return nil, fmt.Errorf("template %q: unexpected '}'", s)
+		}
+		if i > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:i]})
+		}
+
+		j := strings.IndexAny(rest[i+1:], "{}")
+		if j < 0 || rest[i+1+j] == '{' {
+			return nil, fmt.Errorf("template %q: unclosed expression", s)
+		}
+
+		expr, err := parseTemplateExpr(rest[i+1 : i+1+j])
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", s, err)
+		}
+		t.parts = append(t.parts, templatePart{expr: expr})
+
+		rest = rest[i+1+j+1:]
+	}
+
+	return t, nil
+}
+
+// MustParseTemplate is like ParseTemplate but panics on errors.
+func MustParseTemplate(s string) *Template {
+	t, err := ParseTemplate(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+var rxTemplateVarname = regexp.MustCompile(`^(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2})(?:\.?(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2}))*$`)
+
+func parseTemplateExpr(s string) (*templateExpr, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	expr := &templateExpr{op: templateOps[0]}
+	if op, ok := templateOps[s[0]]; ok && s[0] != 0 {
+		expr.op = op
+		s = s[1:]
+	} else if strings.IndexByte("=,!@|", s[0]) >= 0 {
+		return nil, fmt.Errorf("reserved operator %q", s[0])
+	}
+
+	for _, spec := range strings.Split(s, ",") {
+		var v templateVar
+		if strings.HasSuffix(spec, "*") {
+			v.explode = true
+			spec = spec[:len(spec)-1]
+		} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+			prefix, err := strconv.Atoi(spec[i+1:])
+			if err != nil || prefix <= 0 || prefix >= 10000 || spec[i+1] == '0' {
+				return nil, fmt.Errorf("invalid prefix modifier %q", spec[i:])
+			}
+			v.prefix = prefix
+			spec = spec[:i]
+		}
+
+		if !rxTemplateVarname.MatchString(spec) {
+			return nil, fmt.Errorf("invalid variable name %q", spec)
+		}
+		v.name = spec
+
+		expr.vars = append(expr.vars, v)
+	}
+
+	return expr, nil
+}
+
+// String returns the template as it was parsed.
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Varnames returns the names of variables in t in the order of appearance.
+func (t *Template) Varnames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, p := range t.parts {
+		if p.expr == nil {
+			continue
+		}
+		for _, v := range p.expr.vars {
+			if !seen[v.name] {
+				seen[v.name] = true
+				names = append(names, v.name)
+			}
+		}
+	}
+	return names
+}
+
+// Expand expands t with vars into a URI reference string.
+func (t *Template) Expand(vars map[string]interface{}) (string, error) {
+	var b strings.Builder
+	for _, p := range t.parts {
+		if p.expr == nil {
+			b.WriteString(encodeTemplateLiteral(p.literal))
+			continue
+		}
+		if err := p.expr.expand(&b, vars); err != nil {
+			return "", fmt.Errorf("template %q: %w", t.raw, err)
+		}
+	}
+	return b.String(), nil
+}
+
+// ExpandURL expands t with vars and returns the result parsed and normalized by NormalizeURL.
+// Note that the normalization percent-encodes sub-delimiters like "," in paths and queries.
+func (t *Template) ExpandURL(vars map[string]interface{}) (*url.URL, error) {
+	s, err := t.Expand(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return NormalizeURL(u)
+}
+
+func (e *templateExpr) expand(b *strings.Builder, vars map[string]interface{}) error {
+	first := true
+	for _, v := range e.vars {
+		value, defined, err := templateValue(vars[v.name])
+		if err != nil {
+			return fmt.Errorf("variable %q: %w", v.name, err)
+		}
+		if !defined {
+			continue
+		}
+
+		if first {
+			b.WriteString(e.op.first)
+			first = false
+		} else {
+			b.WriteString(e.op.sep)
+		}
+
+		switch value := value.(type) {
+		case string:
+			if v.prefix > 0 {
+				value = truncateRunes(value, v.prefix)
+			}
+			e.writeNamed(b, v.name, value)
+
+		case []string:
+			if v.prefix > 0 {
+				return fmt.Errorf("variable %q: prefix modifier applied to a list", v.name)
+			}
+			if v.explode {
+				for i, item := range value {
+					if i > 0 {
+						b.WriteString(e.op.sep)
+					}
+					e.writeNamed(b, v.name, item)
+				}
+			} else {
+				if e.op.named {
+					b.WriteString(v.name + "=")
+				}
+				for i, item := range value {
+					if i > 0 {
+						b.WriteString(",")
+					}
+					b.WriteString(e.encode(item))
+				}
+			}
+
+		case map[string]string:
+			if v.prefix > 0 {
+				return fmt.Errorf("variable %q: prefix modifier applied to a map", v.name)
+			}
+			keys := make([]string, 0, len(value))
+			for k := range value {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			if v.explode {
+				for i, k := range keys {
+					if i > 0 {
+						b.WriteString(e.op.sep)
+					}
+					if e.op.named {
+						e.writeNamed(b, k, value[k])
+					} else {
+						b.WriteString(e.encode(k) + "=" + e.encode(value[k]))
+					}
+				}
+			} else {
+				if e.op.named {
+					b.WriteString(v.name + "=")
+				}
+				for i, k := range keys {
+					if i > 0 {
+						b.WriteString(",")
+					}
+					b.WriteString(e.encode(k) + "," + e.encode(value[k]))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *templateExpr) writeNamed(b *strings.Builder, name, value string) {
+	if e.op.named {
+		b.WriteString(e.encode(name))
+		if value == "" {
+			b.WriteString(e.op.ifEmpty)
+			return
+		}
+		b.WriteString("=")
+	}
+	b.WriteString(e.encode(value))
+}
+
+func (e *templateExpr) encode(s string) string {
+	return encodeTemplateValue(s, e.op.allowReserved)
+}
+
+// templateValue converts a variable value into string, []string or map[string]string.
+func templateValue(v interface{}) (interface{}, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		return v, true, nil
+	case []string:
+		return v, len(v) > 0, nil
+	case map[string]string:
+		return v, len(v) > 0, nil
+	case []interface{}, map[string]interface{}:
+		return nil, false, fmt.Errorf("unsupported value type %T", v)
+	default:
+		return fmt.Sprint(v), true, nil
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+const templateReserved = ":/?#[]@!$&'()*+,;="
+
+func isTemplateUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0
+}
+
+func encodeTemplateValue(s string, allowReserved bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isTemplateUnreserved(c):
+			b.WriteByte(c)
+		case allowReserved && strings.IndexByte(templateReserved, c) >= 0:
+			b.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// encodeTemplateLiteral percent-encodes characters in literals which are not allowed in URIs.
+func encodeTemplateLiteral(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c >= utf8.RuneSelf || strings.IndexByte(`"'%<>\^`+"`{|}", c) >= 0 {
+			return encodeTemplateValue(s, true)
+		}
+	}
+	return s
+}
+
+// Match matches u against t, and returns the values of variables which reproduce u when expanded.
+// The scheme, host and port of u are normalized like NormalizeURL does before matching,
+// while the other components are left as is, as their normalization may encode delimiters
+// of expansions. See MatchString for details.
+func (t *Template) Match(u *url.URL) (map[string]interface{}, bool) {
+	u = CloneURL(u)
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	port, err := normalizePort(u.Port(), u.Scheme)
+	if err != nil {
+		return nil, false
+	}
+	hostname, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return nil, false
+	}
+	u.Host = hostname
+	if port != "" {
+		u.Host += ":" + port
+	}
+
+	return t.MatchString(u.String())
+}
+
+// MatchString matches s against t, and returns the values of variables which reproduce s when expanded.
+// Values are string or []string, or map[string]string for exploded variables matched with "key=value" pairs.
+// Values of query expressions ("?" and "&") are decoded as forms, so "+" means a space.
+// Variables not appearing in s are omitted from the result.
+//
+// As expansions are not always reversible, the result is only a best guess: for example,
+// a non-exploded list and a map are both matched as a list, and adjacent variables are matched
+// as short as possible.
+func (t *Template) MatchString(s string) (map[string]interface{}, bool) {
+	t.matchOnce.Do(t.compileMatcher)
+
+	m := t.rx.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false
+	}
+
+	vars := map[string]interface{}{}
+	i := 1
+	for _, p := range t.parts {
+		if p.expr == nil {
+			continue
+		}
+		if !p.expr.match(m[i], vars) {
+			return nil, false
+		}
+		i++
+	}
+
+	return vars, true
+}
+
+// templateMatchPatterns are patterns of expansions of expressions without the first strings, by operators.
+var templateMatchPatterns = map[byte]string{
+	0:   `[^/?#&]*?`,
+	'+': `[^#]*?`,
+	'#': `.*?`,
+	'.': `[^/?#]*?`,
+	'/': `[^?#]*?`,
+	';': `[^/?#]*?`,
+	'?': `[^#]*?`,
+	'&': `[^#]*?`,
+}
+
+func (t *Template) compileMatcher() {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, p := range t.parts {
+		if p.expr == nil {
+			b.WriteString(regexp.QuoteMeta(encodeTemplateLiteral(p.literal)))
+			continue
+		}
+
+		pattern := "(" + templateMatchPatterns[p.expr.op.char] + ")"
+		if p.expr.op.first != "" {
+			pattern = "(?:" + regexp.QuoteMeta(p.expr.op.first) + pattern + ")?"
+		}
+		b.WriteString(pattern)
+	}
+	b.WriteString("$")
+
+	t.rx = regexp.MustCompile(b.String())
+}
+
+// match decodes the expansion of e without the first string into vars.
+func (e *templateExpr) match(s string, vars map[string]interface{}) bool {
+	if s == "" {
+		return true
+	}
+
+	pieces := strings.Split(s, e.op.sep)
+
+	if e.op.named {
+		return e.matchNamed(pieces, vars)
+	}
+
+	for i, v := range e.vars {
+		if i >= len(pieces) {
+			return true
+		}
+
+		if v.explode && i == len(e.vars)-1 {
+			value, ok := e.matchExploded(pieces[i:])
+			if !ok {
+				return false
+			}
+			vars[v.name] = value
+			return true
+		}
+
+		value, ok := e.matchValue(pieces[i])
+		if !ok {
+			return false
+		}
+		vars[v.name] = value
+	}
+
+	if len(pieces) > len(e.vars) {
+		// A single non-exploded list separated by ","
+		if len(e.vars) == 1 && e.op.sep == "," {
+			items, ok := e.unescapeAll(pieces)
+			if !ok {
+				return false
+			}
+			vars[e.vars[0].name] = items
+			return true
+		}
+		return false
+	}
+
+	return true
+}
+
+func (e *templateExpr) matchNamed(pieces []string, vars map[string]interface{}) bool {
+	var exploded *templateVar
+	for i, v := range e.vars {
+		if v.explode {
+			exploded = &e.vars[i]
+		}
+	}
+
+	for _, piece := range pieces {
+		name, value := piece, ""
+		if i := strings.IndexByte(piece, '='); i >= 0 {
+			name, value = piece[:i], piece[i+1:]
+		}
+		name, err := e.unescape(name)
+		if err != nil {
+			return false
+		}
+
+		var spec *templateVar
+		for i, v := range e.vars {
+			if v.name == name {
+				spec = &e.vars[i]
+				break
+			}
+		}
+
+		switch {
+		case spec != nil && spec.explode:
+			value, err := e.unescape(value)
+			if err != nil {
+				return false
+			}
+			list, _ := vars[name].([]string)
+			vars[name] = append(list, value)
+
+		case spec != nil:
+			if _, ok := vars[name]; ok {
+				return false
+			}
+			v, ok := e.matchValue(value)
+			if !ok {
+				return false
+			}
+			vars[name] = v
+
+		case exploded != nil:
+			value, err := e.unescape(value)
+			if err != nil {
+				return false
+			}
+			m, ok := vars[exploded.name].(map[string]string)
+			if !ok {
+				if _, defined := vars[exploded.name]; defined {
+					return false
+				}
+				m = map[string]string{}
+				vars[exploded.name] = m
+			}
+			m[name] = value
+
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func (e *templateExpr) unescape(s string) (string, error) {
+	if e.op.char == '?' || e.op.char == '&' {
+		return url.QueryUnescape(s)
+	}
+	return url.PathUnescape(s)
+}
+
+// matchValue decodes a value of a non-exploded variable, which is a list if it contains ",".
+func (e *templateExpr) matchValue(s string) (interface{}, bool) {
+	if strings.Contains(s, ",") {
+		items, ok := e.unescapeAll(strings.Split(s, ","))
+		return items, ok
+	}
+
+	v, err := e.unescape(s)
+	return v, err == nil
+}
+
+// matchExploded decodes values of an exploded variable, which is a map if all of them are "key=value" pairs.
+func (e *templateExpr) matchExploded(pieces []string) (interface{}, bool) {
+	m := map[string]string{}
+	for _, piece := range pieces {
+		i := strings.IndexByte(piece, '=')
+		if i < 0 {
+			m = nil
+			break
+		}
+		k, err1 := e.unescape(piece[:i])
+		v, err2 := e.unescape(piece[i+1:])
+		if err1 != nil || err2 != nil {
+			return nil, false
+		}
+		m[k] = v
+	}
+	if m != nil {
+		return m, true
+	}
+
+	items, ok := e.unescapeAll(pieces)
+	return items, ok
+}
+
+func (e *templateExpr) unescapeAll(ss []string) ([]string, bool) {
+	result := make([]string, len(ss))
+	for i, s := range ss {
+		v, err := e.unescape(s)
+		if err != nil {
+			return nil, false
+		}
+		result[i] = v
+	}
+	return result, true
+}
@@ -0,0 +1,311 @@
+package urlutil
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// Examples from RFC 6570, with map keys in the sorted order
+var templateTestVars = map[string]interface{}{
+	"count":      []string{"one", "two", "three"},
+	"dom":        []string{"example", "com"},
+	"dub":        "me/too",
+	"hello":      "Hello World!",
+	"half":       "50%",
+	"var":        "value",
+	"who":        "fred",
+	"base":       "http://example.com/home/",
+	"path":       "/foo/bar",
+	"list":       []string{"red", "green", "blue"},
+	"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+	"v":          "6",
+	"x":          1024,
+	"y":          "768",
+	"empty":      "",
+	"empty_keys": map[string]string{},
+	"undef":      nil,
+}
+
+func TestTemplate_Expand(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		// Level 1
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{half}", "50%25"},
+		{"O{empty}X", "OX"},
+		{"O{undef}X", "OX"},
+
+		// Level 2
+		{"{+var}", "value"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+half}", "50%25"},
+		{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{"{+base}index", "http://example.com/home/index"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"here?ref={+path}", "here?ref=/foo/bar"},
+		{"X{#var}", "X#value"},
+		{"X{#hello}", "X#Hello%20World!"},
+
+		// Level 3
+		{"map?{x,y}", "map?1024,768"},
+		{"{x,hello,y}", "1024,Hello%20World%21,768"},
+		{"{+x,hello,y}", "1024,Hello%20World!,768"},
+		{"{+path,x}/here", "/foo/bar,1024/here"},
+		{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+		{"{#path,x}/here", "#/foo/bar,1024/here"},
+		{"X{.var}", "X.value"},
+		{"X{.x,y}", "X.1024.768"},
+		{"{/var}", "/value"},
+		{"{/var,x}/here", "/value/1024/here"},
+		{"{;x,y}", ";x=1024;y=768"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{?x,y}", "?x=1024&y=768"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{&x,y,empty}", "&x=1024&y=768&empty="},
+		{"{?undef,x}", "?x=1024"},
+
+		// Level 4
+		{"{var:3}", "val"},
+		{"{var:30}", "value"},
+		{"{list}", "red,green,blue"},
+		{"{list*}", "red,green,blue"},
+		{"{keys}", "comma,%2C,dot,.,semi,%3B"},
+		{"{keys*}", "comma=%2C,dot=.,semi=%3B"},
+		{"{empty_keys}", ""},
+		{"{+path:6}/here", "/foo/b/here"},
+		{"{+list}", "red,green,blue"},
+		{"{+list*}", "red,green,blue"},
+		{"{+keys}", "comma,,,dot,.,semi,;"},
+		{"{+keys*}", "comma=,,dot=.,semi=;"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{#list}", "#red,green,blue"},
+		{"{#list*}", "#red,green,blue"},
+		{"{#keys}", "#comma,,,dot,.,semi,;"},
+		{"{#keys*}", "#comma=,,dot=.,semi=;"},
+		{"X{.var:3}", "X.val"},
+		{"X{.list}", "X.red,green,blue"},
+		{"X{.list*}", "X.red.green.blue"},
+		{"X{.keys}", "X.comma,%2C,dot,.,semi,%3B"},
+		{"X{.keys*}", "X.comma=%2C.dot=..semi=%3B"},
+		{"www{.dom*}", "www.example.com"},
+		{"{/var:1,var}", "/v/value"},
+		{"{/list}", "/red,green,blue"},
+		{"{/list*}", "/red/green/blue"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{/keys}", "/comma,%2C,dot,.,semi,%3B"},
+		{"{/keys*}", "/comma=%2C/dot=./semi=%3B"},
+		{"{;hello:5}", ";hello=Hello"},
+		{"{;list}", ";list=red,green,blue"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{;keys}", ";keys=comma,%2C,dot,.,semi,%3B"},
+		{"{;keys*}", ";comma=%2C;dot=.;semi=%3B"},
+		{"{?var:3}", "?var=val"},
+		{"{?list}", "?list=red,green,blue"},
+		{"{?list*}", "?list=red&list=green&list=blue"},
+		{"{?keys}", "?keys=comma,%2C,dot,.,semi,%3B"},
+		{"{?keys*}", "?comma=%2C&dot=.&semi=%3B"},
+		{"{&var:3}", "&var=val"},
+		{"{&list}", "&list=red,green,blue"},
+		{"{&list*}", "&list=red&list=green&list=blue"},
+		{"{&keys}", "&keys=comma,%2C,dot,.,semi,%3B"},
+		{"{&keys*}", "&comma=%2C&dot=.&semi=%3B"},
+
+		// Non-ASCII
+		{"{who:2}/{hello:1}-{var}", "fr/H-value"},
+		{"/café/{+dub}", "/caf%C3%A9/me/too"},
+	}
+
+	for _, test := range tests {
+		tmpl, err := ParseTemplate(test.template)
+		if err != nil {
+			t.Errorf("ParseTemplate(%q): %v", test.template, err)
+			continue
+		}
+
+		got, err := tmpl.Expand(templateTestVars)
+		if err != nil {
+			t.Errorf("%q: Expand: %v", test.template, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: Expand = %q, want %q", test.template, got, test.want)
+		}
+	}
+}
+
+func TestTemplate_Errors(t *testing.T) {
+	for _, s := range []string{
+		"{var",
+		"var}",
+		"{va{r}",
+		"{}",
+		"{=var}",
+		"{|var}",
+		"{var:0}",
+		"{var:10000}",
+		"{var:3*}",
+		"{a b}",
+		"{.}",
+	} {
+		if _, err := ParseTemplate(s); err == nil {
+			t.Errorf("ParseTemplate(%q) should fail", s)
+		}
+	}
+
+	for _, s := range []string{"{list:3}", "{keys:3}"} {
+		if _, err := MustParseTemplate(s).Expand(templateTestVars); err == nil {
+			t.Errorf("%q: Expand should fail", s)
+		}
+	}
+}
+
+func TestTemplate_ExpandURL(t *testing.T) {
+	tmpl := MustParseTemplate("HTTPS://API.Example.com:443/repos/{owner}/{repo}/issues{?state,labels}")
+
+	u, err := tmpl.ExpandURL(map[string]interface{}{
+		"owner":  "motemen",
+		"repo":   "go-nuts",
+		"state":  "open",
+		"labels": []string{"bug", "help wanted"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "https://api.example.com/repos/motemen/go-nuts/issues?state=open&labels=bug%2Chelp+wanted"
+	if got := u.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTemplate_Match(t *testing.T) {
+	tests := []struct {
+		template string
+		in       string
+		want     map[string]interface{}
+		wantFail bool
+	}{
+		{
+			template: "http://example.com/users/{user}/repos/{repo}",
+			in:       "http://EXAMPLE.com:80/users/motemen/repos/go-nuts",
+			want:     map[string]interface{}{"user": "motemen", "repo": "go-nuts"},
+		},
+		{
+			template: "http://example.com/search{?q,lang}",
+			in:       "http://example.com/search?q=hello%20world&lang=ja",
+			want:     map[string]interface{}{"q": "hello world", "lang": "ja"},
+		},
+		{
+			template: "http://example.com/search{?q}",
+			in:       "http://example.com/search?q=a+b%2Cc",
+			want:     map[string]interface{}{"q": "a b,c"},
+		},
+		{
+			template: "http://example.com/search{?q,lang}",
+			in:       "http://example.com/search",
+			want:     map[string]interface{}{},
+		},
+		{
+			template: "http://example.com/files{/path*}{?list*}",
+			in:       "http://example.com/files/a/b/c.txt?list=red&list=green",
+			want: map[string]interface{}{
+				"path": []string{"a", "b", "c.txt"},
+				"list": []string{"red", "green"},
+			},
+		},
+		{
+			template: "http://example.com/{+path}{#frag}",
+			in:       "http://example.com/foo/bar#top",
+			want:     map[string]interface{}{"path": "foo/bar", "frag": "top"},
+		},
+		{
+			template: "http://example.com/map{?keys*}",
+			in:       "http://example.com/map?comma=%2C&dot=.",
+			want: map[string]interface{}{
+				"keys": map[string]string{"comma": ",", "dot": "."},
+			},
+		},
+		{
+			template: "http://example.com/{x,y}{;list}",
+			in:       "http://example.com/1024,768;list=red,green",
+			want: map[string]interface{}{
+				"x":    "1024",
+				"y":    "768",
+				"list": []string{"red", "green"},
+			},
+		},
+		{
+			template: "http://www{.dom*}/{list}",
+			in:       "http://www.example.com/red,green,blue",
+			want: map[string]interface{}{
+				"dom":  []string{"example", "com"},
+				"list": []string{"red", "green", "blue"},
+			},
+		},
+		{
+			template: "http://example.com/users/{user}",
+			in:       "http://example.com/repos/motemen",
+			wantFail: true,
+		},
+		{
+			template: "http://example.com/search{?q}",
+			in:       "http://example.com/search?other=1",
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		u, err := url.Parse(test.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, ok := MustParseTemplate(test.template).Match(u)
+		if test.wantFail {
+			if ok {
+				t.Errorf("%q: Match(%q) = %v, want failure", test.template, test.in, got)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q: Match(%q) failed", test.template, test.in)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: Match(%q) = %#v, want %#v", test.template, test.in, got, test.want)
+		}
+	}
+}
+
+func TestTemplate_MatchRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"{var}", "{hello}", "{+path}/here", "X{.x,y}", "{/list*}", "{;x,y,empty}",
+		"{?x,y,empty}", "{?list*}", "{&keys*}", "X{#hello}",
+	} {
+		tmpl := MustParseTemplate(s)
+
+		expanded, err := tmpl.Expand(templateTestVars)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		vars, ok := tmpl.MatchString(expanded)
+		if !ok {
+			t.Errorf("%q: MatchString(%q) failed", s, expanded)
+			continue
+		}
+
+		got, err := tmpl.Expand(vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expanded {
+			t.Errorf("%q: expanded matched variables %#v to %q, want %q", s, vars, got, expanded)
+		}
+	}
+}